@@ -0,0 +1,313 @@
+// Tools runs a program under monitoring and controls a running one:
+//
+//	tools run [flags] -- command [args...]
+//	tools status [-socket path]
+//	tools stop [-socket path]
+//
+// Flags of run override fields of a config file set by -config. A program runs in its own
+// process group by default, so a stop kills its children as well.
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/7phs/tools/monitoring"
+	"github.com/7phs/tools/shutdown"
+)
+
+const (
+	requestTimeout = 30 * time.Second
+)
+
+var defaultSocket = filepath.Join(os.TempDir(), "tools.sock")
+
+// configParameter is implemented by a parameter of a config file.
+type configParameter interface {
+	monitoring.MonitoringParameter
+	monitoring.EnvParameter
+	monitoring.RestartRuleParameter
+	monitoring.LifetimeParameter
+	monitoring.StopParameter
+	monitoring.ProcessGroupParameter
+}
+
+// parameter passes an output of a program to the terminal.
+type parameter struct {
+	configParameter
+}
+
+func (o *parameter) StdOutSink() monitoring.OutputSink {
+	return monitoring.WriterSink(os.Stdout)
+}
+
+func (o *parameter) StdErrSink() monitoring.OutputSink {
+	return monitoring.WriterSink(os.Stderr)
+}
+
+type envFlag map[string]string
+
+func (o envFlag) String() string {
+	values := make([]string, 0, len(o))
+	for key, value := range o {
+		values = append(values, key+"="+value)
+	}
+
+	return strings.Join(values, ",")
+}
+
+func (o envFlag) Set(value string) error {
+	parts := strings.SplitN(value, "=", 2)
+	if len(parts) != 2 {
+		return errors.Errorf("'%s' isn't KEY=value", value)
+	}
+
+	o[parts[0]] = parts[1]
+
+	return nil
+}
+
+func main() {
+	if len(os.Args) < 2 {
+		usage(os.Stderr)
+		os.Exit(2)
+	}
+
+	var err error
+
+	switch os.Args[1] {
+	case "run":
+		err = run(os.Args[2:])
+
+	case "status":
+		err = status(os.Args[2:])
+
+	case "stop":
+		err = stop(os.Args[2:])
+
+	case "help", "-h", "-help", "--help":
+		usage(os.Stdout)
+		return
+
+	default:
+		usage(os.Stderr)
+		os.Exit(2)
+	}
+
+	if err == flag.ErrHelp {
+		return
+	}
+
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "tools:", err)
+		os.Exit(1)
+	}
+}
+
+func usage(w io.Writer) {
+	fmt.Fprintln(w, "Usage:")
+	fmt.Fprintln(w, "  tools run [flags] -- command [args...]")
+	fmt.Fprintln(w, "  tools status [-socket path]")
+	fmt.Fprintln(w, "  tools stop [-socket path]")
+	fmt.Fprintln(w, "Run 'tools <command> -h' to list flags of a command.")
+}
+
+// parseRun builds a config of a program from a config file and flags.
+func parseRun(args []string) (config monitoring.ParameterConfig, socket string, err error) {
+	var (
+		flags      = flag.NewFlagSet("run", flag.ContinueOnError)
+		configPath = flags.String("config", "", "a YAML, JSON or TOML config file of a program")
+		workDir    = flags.String("workdir", "", "a working directory of a program")
+		parallel   = flags.Int("parallel", 1, "a count of parallel instances")
+		mode       = flags.String("mode", monitoring.ModeInfinity, "a running mode: infinity, once or a count of repeats")
+		restart    = flags.String("restart", "always", "a restart mode: always, on-failure or on-abnormal")
+		startLine  = flags.String("start-line", "", "a regular expression of a line reporting a program has started")
+		stdErrIsOk = flags.Bool("stderr-ok", false, "an output to stderr isn't a failure of a start")
+		lifetime   = flags.String("max-lifetime", "", "a duration to restart every instance after, like 1h")
+		jitter     = flags.Float64("lifetime-jitter", 0, "a part of a lifetime to randomize it by, in [0, 1]")
+		maxRuns    = flags.Int("max-runs", 0, "a count of runs to recycle an instance after, it restarts regardless of a restart mode")
+		stopSignal = flags.String("stop-signal", "", "a signal to stop a program gracefully, like TERM, it is killed otherwise")
+		stopWait   = flags.Duration("stop-timeout", 0, "a duration to wait for a program to stop before a kill, 10s by default")
+		group      = flags.Bool("process-group", true, "run a program in its own process group to stop its children with it")
+		env        = envFlag{}
+	)
+
+	flags.Var(env, "env", "a KEY=value variable of the environment, could be repeated")
+	flags.StringVar(&socket, "socket", defaultSocket, "a control socket of status and stop commands")
+
+	if err = flags.Parse(args); err != nil {
+		return
+	}
+
+	// a config file could disable a process group
+	config.ProcessGroup = true
+
+	if *configPath != "" {
+		if err = monitoring.ReadParameterConfig(*configPath, &config); err != nil {
+			return
+		}
+	}
+
+	// only set flags override a config file
+	flags.Visit(func(f *flag.Flag) {
+		switch f.Name {
+		case "workdir":
+			config.WorkDir = *workDir
+		case "parallel":
+			config.ParallelCount = int32(*parallel)
+		case "mode":
+			config.RunningMode = *mode
+		case "restart":
+			config.Restart = *restart
+		case "start-line":
+			config.StartLine = *startLine
+		case "stderr-ok":
+			config.StdErrIsOk = *stdErrIsOk
+		case "max-lifetime":
+			config.MaxLifetime = *lifetime
+		case "lifetime-jitter":
+			config.LifetimeJitter = *jitter
+		case "max-runs":
+			config.MaxRuns = int32(*maxRuns)
+		case "stop-signal":
+			config.StopSignal = *stopSignal
+		case "stop-timeout":
+			config.StopTimeout = stopWait.String()
+		case "process-group":
+			config.ProcessGroup = *group
+		case "env":
+			if config.Env == nil {
+				config.Env = make(map[string]string)
+			}
+			for key, value := range env {
+				config.Env[key] = value
+			}
+		}
+	})
+
+	if flags.NArg() > 0 {
+		config.Command, config.Args = flags.Arg(0), flags.Args()[1:]
+	}
+
+	return
+}
+
+func run(args []string) error {
+	config, socket, err := parseRun(args)
+	if err != nil {
+		return err
+	}
+
+	fileParameter, err := monitoring.NewFileParameter(config)
+	if err != nil {
+		return err
+	}
+
+	program := monitoring.NewMonitoring(&parameter{fileParameter})
+
+	server, err := monitoring.ListenControl("unix", socket, program)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+
+		server.Close(ctx)
+	}()
+
+	shutdown.Register(func() {
+		program.Stop(context.Background())
+	})
+
+	program.Start(context.Background())
+	program.Wait()
+
+	return program.HasError()
+}
+
+// parseSocket parses flags of commands talking to a running program.
+func parseSocket(name string, args []string) (string, error) {
+	var (
+		flags  = flag.NewFlagSet(name, flag.ContinueOnError)
+		socket = flags.String("socket", defaultSocket, "a control socket of a running program")
+	)
+
+	err := flags.Parse(args)
+
+	return *socket, err
+}
+
+func status(args []string) error {
+	socket, err := parseSocket("status", args)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), requestTimeout)
+	defer cancel()
+
+	state, err := monitoring.DialControl("unix", socket).Status(ctx)
+	if err != nil {
+		return err
+	}
+
+	printStatus(os.Stdout, state)
+
+	return nil
+}
+
+func stop(args []string) error {
+	socket, err := parseSocket("stop", args)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), requestTimeout)
+	defer cancel()
+
+	state, err := monitoring.DialControl("unix", socket).Stop(ctx)
+	if err != nil {
+		return err
+	}
+
+	printStatus(os.Stdout, state)
+
+	return nil
+}
+
+func printStatus(w io.Writer, status monitoring.Status) {
+	fmt.Fprintln(w, "stage:", status.Stage)
+	if status.Error != "" {
+		fmt.Fprintln(w, "error:", status.Error)
+	}
+
+	table := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(table, "INSTANCE\tPID\tPORT\tRUNS\tSTATE\tSTARTED")
+
+	for _, instance := range status.Instances {
+		state := "running"
+		switch {
+		case instance.Stopped:
+			state = "stopped"
+		case instance.Exited:
+			state = "exited"
+		case instance.Unhealthy:
+			state = "unhealthy"
+		}
+
+		fmt.Fprintf(table, "%d\t%d\t%d\t%d\t%s\t%s\n", instance.Index, instance.Pid, instance.Port,
+			instance.Runs, state, instance.StartTime.Format(time.RFC3339))
+	}
+
+	table.Flush()
+}
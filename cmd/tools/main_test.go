@@ -0,0 +1,157 @@
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/7phs/tools/monitoring"
+)
+
+func TestParseRun(t *testing.T) {
+	config, socket, err := parseRun([]string{"--parallel", "4", "--restart", "on-failure", "--env", "A=1", "--", "myserver", "--flag"})
+	if err != nil {
+		t.Error("failed to parse flags with", err)
+		return
+	}
+
+	expected := monitoring.ParameterConfig{
+		Command:       "myserver",
+		Args:          []string{"--flag"},
+		Env:           map[string]string{"A": "1"},
+		ParallelCount: 4,
+		Restart:       "on-failure",
+		ProcessGroup:  true,
+	}
+	if !reflect.DeepEqual(config, expected) {
+		t.Error("failed to parse flags. Got", config, ", but expected is", expected)
+	}
+
+	if socket != defaultSocket {
+		t.Error("failed to use a default socket. Got", socket, ", but expected is", defaultSocket)
+	}
+
+	if _, _, err = parseRun([]string{"--env", "A"}); err == nil {
+		t.Error("failed to catch an error of an invalid variable")
+	}
+}
+
+func TestParseRun_Config(t *testing.T) {
+	dir, err := ioutil.TempDir("", "tools-cmd")
+	if err != nil {
+		t.Error("failed to create a temp dir with", err)
+		return
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "program.yaml")
+	data := "command: sh\nargs: [-c, sleep 1]\nparallel_count: 2\nrunning_mode: once\nenv:\n  A: \"1\"\n"
+	if err = ioutil.WriteFile(path, []byte(data), 0644); err != nil {
+		t.Error("failed to write a config with", err)
+		return
+	}
+
+	config, socket, err := parseRun([]string{"-config", path, "-parallel", "3", "-env", "B=2", "-socket", "/tmp/test.sock"})
+	if err != nil {
+		t.Error("failed to parse flags with", err)
+		return
+	}
+
+	expected := monitoring.ParameterConfig{
+		Command:       "sh",
+		Args:          []string{"-c", "sleep 1"},
+		Env:           map[string]string{"A": "1", "B": "2"},
+		ParallelCount: 3,
+		RunningMode:   monitoring.ModeOnce,
+		ProcessGroup:  true,
+	}
+	if !reflect.DeepEqual(config, expected) {
+		t.Error("failed to override a config. Got", config, ", but expected is", expected)
+	}
+
+	if socket != "/tmp/test.sock" {
+		t.Error("failed to parse a socket. Got", socket)
+	}
+
+	if _, _, err = parseRun([]string{"-config", filepath.Join(dir, "unknown.yaml")}); err == nil {
+		t.Error("failed to catch an error of a missing config")
+	}
+}
+
+func TestParseRun_Lifetime(t *testing.T) {
+	config, _, err := parseRun([]string{"-max-lifetime", "1h", "-lifetime-jitter", "0.1", "-max-runs", "3", "myserver"})
+	if err != nil {
+		t.Error("failed to parse flags with", err)
+		return
+	}
+
+	expected := monitoring.ParameterConfig{
+		Command:        "myserver",
+		Args:           []string{},
+		MaxLifetime:    "1h",
+		LifetimeJitter: 0.1,
+		MaxRuns:        3,
+		ProcessGroup:   true,
+	}
+	if !reflect.DeepEqual(config, expected) {
+		t.Error("failed to parse flags of a lifetime. Got", config, ", but expected is", expected)
+	}
+}
+
+func TestParseRun_Stop(t *testing.T) {
+	config, _, err := parseRun([]string{"-stop-signal", "TERM", "-stop-timeout", "5s", "-process-group=false", "myserver"})
+	if err != nil {
+		t.Error("failed to parse flags with", err)
+		return
+	}
+
+	expected := monitoring.ParameterConfig{
+		Command:     "myserver",
+		Args:        []string{},
+		StopSignal:  "TERM",
+		StopTimeout: "5s",
+	}
+	if !reflect.DeepEqual(config, expected) {
+		t.Error("failed to parse flags of a stop. Got", config, ", but expected is", expected)
+	}
+
+	dir, err := ioutil.TempDir("", "tools-cmd")
+	if err != nil {
+		t.Error("failed to create a temp dir with", err)
+		return
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "program.yaml")
+	if err = ioutil.WriteFile(path, []byte("command: sh\nprocess_group: false\n"), 0644); err != nil {
+		t.Error("failed to write a config with", err)
+		return
+	}
+
+	if config, _, err = parseRun([]string{"-config", path}); err != nil || config.ProcessGroup {
+		t.Error("failed to disable a process group by a config. Got", config.ProcessGroup, err)
+	}
+}
+
+func TestPrintStatus(t *testing.T) {
+	var out bytes.Buffer
+
+	printStatus(&out, monitoring.Status{
+		Stage: "monitoring",
+		Instances: []monitoring.InstanceStatus{
+			{Index: 0, Pid: 100, Runs: 1, StartTime: time.Unix(0, 0).UTC()},
+			{Index: 1, Pid: 101, Runs: 2, Exited: true, StartTime: time.Unix(0, 0).UTC()},
+		},
+	})
+
+	for _, expected := range []string{"stage: monitoring", "100", "running", "101", "exited"} {
+		if !strings.Contains(out.String(), expected) {
+			t.Error("failed to print", expected, ". Got", out.String())
+		}
+	}
+}
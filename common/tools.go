@@ -29,7 +29,7 @@ func RandomRange(min, max int, values ... int) *randomRange {
 }
 
 func (o *randomRange) init(values ... int) *randomRange {
-	for value := range values {
+	for _, value := range values {
 		o.checked[value] = true
 	}
 
@@ -86,7 +86,7 @@ func takeArgs(ln int, minMaximum ... int) []int {
 	return minMaximum[:ln]
 }
 
-func isPortAvailable(port int) bool {
+func IsPortAvailable(port int) bool {
 	if ln, err := net.Listen("tcp", fmt.Sprintf(":%d", port)); err != nil {
 		return false
 	} else {
@@ -101,7 +101,7 @@ func CheckLocalPort(port int, minMaximum ... int) int {
 
 	random := RandomRange(min, max, port)
 
-	for i := 0; !isPortAvailable(port) && i < tryRandomCount; i++ {
+	for i := 0; !IsPortAvailable(port) && i < tryRandomCount; i++ {
 		port = random.Int()
 	}
 
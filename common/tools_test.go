@@ -121,6 +121,20 @@ func TestRandomRange(t *testing.T) {
 	}
 }
 
+func TestRandomRange_Exclude(t *testing.T) {
+	random := RandomRange(100, 200, 120, 180)
+
+	for _, value := range []int{120, 180} {
+		if !random.checked[value] {
+			t.Error("failed to exclude an initial value ", value)
+		}
+	}
+
+	if len(random.checked) != 2 {
+		t.Error("unexpected excluded values ", random.checked)
+	}
+}
+
 func TestIsPortAvailable(t *testing.T) {
 	random := RandomRange(32000, 64000)
 
@@ -133,7 +147,7 @@ func TestIsPortAvailable(t *testing.T) {
 		}
 		defer ln.Close()
 
-		return isPortAvailable(port)
+		return IsPortAvailable(port)
 	}()
 
 	if exist {
@@ -141,7 +155,7 @@ func TestIsPortAvailable(t *testing.T) {
 	}
 
 	port = random.Int()
-	if !isPortAvailable(port) {
+	if !IsPortAvailable(port) {
 		t.Error("failed to check a free port. Got ", exist, ", but expected is true")
 	}
 }
@@ -1,9 +1,12 @@
 package monitoring
 
 import (
+	"context"
 	"io"
+	"os"
 	"os/exec"
 	"sync"
+	"time"
 
 	"github.com/pkg/errors"
 
@@ -16,12 +19,41 @@ type Parameter interface {
 	ToArgs() []string
 }
 
+// EnvParameter is an optional extension of Parameter to add "KEY=value" variables
+// to the environment of the current process.
+type EnvParameter interface {
+	Env() []string
+}
+
+// StopParameter is an optional extension of Parameter to stop a command gracefully:
+// send StopSignal, wait StopTimeout and kill the command after that.
+type StopParameter interface {
+	StopSignal() os.Signal
+	StopTimeout() time.Duration
+}
+
+// ProcessGroupParameter is an optional extension of Parameter to start a command
+// in its own process group and to signal the whole group on kill and stop.
+type ProcessGroupParameter interface {
+	ProcessGroup() bool
+}
+
+const (
+	defaultStopTimeout = 10 * time.Second
+)
+
 type CoreCmd struct {
 	cmd *exec.Cmd
 
 	stdoutStream chan string
 	stderrStream chan string
 	wait         chan bool
+	startTime    time.Time
+
+	stopSignal   os.Signal
+	stopTimeout  time.Duration
+	processGroup bool
+	attrs        *ProcessAttrs
 
 	monitoringCh    chan bool
 	monitoringClose func()
@@ -38,6 +70,29 @@ func (o *CoreCmd) init(parameter Parameter) *CoreCmd {
 	o.cmd =  exec.Command(parameter.Command(), parameter.ToArgs()...)
 	o.cmd.Dir = parameter.WorkDir()
 
+	if env, ok := parameter.(EnvParameter); ok && len(env.Env()) > 0 {
+		o.cmd.Env = append(os.Environ(), env.Env()...)
+	}
+
+	if stop, ok := parameter.(StopParameter); ok {
+		o.stopSignal = stop.StopSignal()
+		o.stopTimeout = stop.StopTimeout()
+		if o.stopTimeout <= 0 {
+			o.stopTimeout = defaultStopTimeout
+		}
+	}
+
+	if group, ok := parameter.(ProcessGroupParameter); ok && group.ProcessGroup() {
+		o.processGroup = true
+		setProcessGroup(o.cmd)
+	}
+
+	o.attrs = nil
+	if attrs, ok := parameter.(ProcessAttrsParameter); ok && attrs.ProcessAttrs() != nil {
+		o.attrs = attrs.ProcessAttrs()
+		setProcessAttrs(o.cmd, o.attrs)
+	}
+
 	return o
 }
 
@@ -52,9 +107,10 @@ func (o *CoreCmd) prepare() (*CoreCmd, error) {
 	o.stdoutStream, errOut = o.reader2Stream(o.cmd.StdoutPipe())
 	o.stderrStream, errErr = o.reader2Stream(o.cmd.StderrPipe())
 
-	return o, common.SeveralErrors("failed to prepare streams",
+	return o, common.SeveralErrors("failed to prepare command",
 		errors.Wrap(errOut, "failed to prepare stdoutStream"),
 		errors.Wrap(errErr, "failed to prepare stderrStream"),
+		o.attrs.check(),
 	)
 }
 
@@ -83,32 +139,115 @@ func (o *CoreCmd) Wait() <-chan bool {
 }
 
 func (o *CoreCmd) Start() error {
-	if err := o.cmd.Start(); err != nil {
+	if err := startWithAttrs(o.cmd, o.attrs); err != nil {
 		return errors.Wrap(err, "failed to start command")
 	}
 
-	o.wait = make(chan bool)
+	o.startTime = time.Now()
+
+	cmd, wait := o.cmd, make(chan bool)
+	o.wait = wait
 	go func() {
-		o.cmd.Wait()
-		close(o.wait)
+		cmd.Wait()
+		close(wait)
 	}()
 
 	return nil
 }
 
+// Pid returns an id of the process of the command or 0 if it isn't started.
+func (o *CoreCmd) Pid() int {
+	if o.cmd == nil || o.cmd.Process == nil {
+		return 0
+	}
+
+	return o.cmd.Process.Pid
+}
+
+func (o *CoreCmd) StartTime() time.Time {
+	return o.startTime
+}
+
 func (o *CoreCmd) Kill() error {
 	if err:=o.checkProcessState(); err!=nil {
 		return err
 	}
 
-	o.monitoringClose()
-	o.monitoringWait.Wait()
+	o.stopMonitoring()
 
-	err := o.cmd.Process.Kill()
+	err := o.signal(os.Kill)
 
 	return errors.Wrap(err, "failed to kill the command")
 }
 
+// Terminate sends the stop signal and waits for the command to exit till the stop timeout
+// or ctx is over, then kills it. Without the stop signal it kills the command and waits for the exit.
+func (o *CoreCmd) Terminate(ctx context.Context) error {
+	if o.stopSignal == nil {
+		if err := o.Kill(); err != nil {
+			return err
+		}
+		<-o.Wait()
+
+		return nil
+	}
+
+	if err := o.checkProcessState(); err != nil {
+		return err
+	}
+
+	o.stopMonitoring()
+
+	if err := o.signal(o.stopSignal); err != nil {
+		return errors.Wrapf(err, "failed to send %v to the command", o.stopSignal)
+	}
+
+	timer := time.NewTimer(o.stopTimeout)
+	defer timer.Stop()
+
+	select {
+	case <-o.Wait():
+		// children could survive their leader
+		if o.processGroup {
+			o.signal(os.Kill)
+		}
+
+		return nil
+
+	case <-timer.C:
+
+	case <-ctx.Done():
+	}
+
+	if err := o.signal(os.Kill); err != nil {
+		return errors.Wrap(err, "failed to kill the command after the stop timeout")
+	}
+	<-o.Wait()
+
+	return errors.Errorf("the command ignored %v and was killed", o.stopSignal)
+}
+
+// killGroup kills processes left in the group of the command after its leader has exited.
+func (o *CoreCmd) killGroup() {
+	if o.processGroup && o.cmd != nil && o.cmd.Process != nil {
+		signalGroup(o.cmd.Process, os.Kill)
+	}
+}
+
+func (o *CoreCmd) signal(sig os.Signal) error {
+	if o.processGroup {
+		return signalGroup(o.cmd.Process, sig)
+	}
+
+	return o.cmd.Process.Signal(sig)
+}
+
+func (o *CoreCmd) stopMonitoring() {
+	o.monitoringClose()
+	o.monitoringClose = func() {}
+	o.monitoringWait.Wait()
+}
+
 func (o *CoreCmd) monitoring() <-chan bool {
 	return o.monitoringCh
 }
@@ -141,14 +280,25 @@ func (o *CoreCmd) IsExited() bool {
 	return o.cmd!=nil && o.cmd.ProcessState!=nil && o.cmd.ProcessState.Exited()
 }
 
+// exitStatus is valid after the command has finished.
+func (o *CoreCmd) exitStatus() (int, os.Signal) {
+	if o.cmd == nil || o.cmd.ProcessState == nil {
+		return -1, nil
+	}
+
+	return exitStatus(o.cmd.ProcessState)
+}
+
 func (o *CoreCmd) checkProcessState() error {
 	// check start
 	if o.cmd == nil || o.cmd.Process == nil {
 		return errors.New("failed to kill process id-less")
 	}
-	// check finish
-	if o.IsExited() {
+	// check finish, the wait of the command writes ProcessState concurrently
+	select {
+	case <-o.Wait():
 		return errors.New("already killed")
+	default:
 	}
 
 	return nil
@@ -2,12 +2,14 @@ package monitoring
 
 import (
 	"bytes"
+	"context"
 	"errors"
 	"fmt"
 	"math/rand"
 	"strconv"
 	"strings"
 	"sync"
+	"syscall"
 	"testing"
 	"time"
 
@@ -308,3 +310,115 @@ func TestCoreCmd_MonitoringErr(t *testing.T) {
 		return
 	}
 }
+
+func TestCoreCmd_Terminate(t *testing.T) {
+	cmd, err := NewCoreCmd(&testParameter{
+		command:     "sh",
+		args:        []string{"-c", "trap 'exit 0' TERM; while true; do sleep 0.1; done"},
+		stopSignal:  syscall.SIGTERM,
+		stopTimeout: 5 * time.Second,
+	})
+	if cmd == nil || err != nil {
+		t.Error("failed to create command with", err)
+		return
+	}
+
+	if err := cmd.Start(); err != nil {
+		t.Error("failed to start command with", err)
+		return
+	}
+
+	time.Sleep(100 * time.Millisecond)
+
+	start := time.Now()
+	if err := cmd.Terminate(context.Background()); err != nil {
+		t.Error("failed to terminate the command with", err)
+	}
+
+	if exist := time.Since(start); exist >= cmd.stopTimeout {
+		t.Error("failed to terminate the command gracefully. Got duration", exist)
+	}
+
+	if status := cmd.cmd.ProcessState.Sys().(syscall.WaitStatus); status.Signaled() {
+		t.Error("failed to exit by itself. Got signal", status.Signal())
+	}
+}
+
+func TestCoreCmd_TerminateTimeout(t *testing.T) {
+	timeout := 200 * time.Millisecond
+
+	cmd, err := NewCoreCmd(&testParameter{
+		command:     "sh",
+		args:        []string{"-c", "trap '' TERM; while true; do sleep 0.1; done"},
+		stopSignal:  syscall.SIGTERM,
+		stopTimeout: timeout,
+	})
+	if cmd == nil || err != nil {
+		t.Error("failed to create command with", err)
+		return
+	}
+
+	if err := cmd.Start(); err != nil {
+		t.Error("failed to start command with", err)
+		return
+	}
+
+	time.Sleep(100 * time.Millisecond)
+
+	start := time.Now()
+	if err := cmd.Terminate(context.Background()); err == nil {
+		t.Error("failed to report killing after the stop timeout")
+	}
+	<-cmd.Wait()
+
+	if exist := time.Since(start); exist < timeout || exist > 2*timeout {
+		t.Error("failed to kill the command after the stop timeout. Got duration", exist, ", but expected is", timeout)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout/2)
+	defer cancel()
+
+	cmd, _ = NewCoreCmd(&testParameter{
+		command:     "sh",
+		args:        []string{"-c", "trap '' TERM; while true; do sleep 0.1; done"},
+		stopSignal:  syscall.SIGTERM,
+		stopTimeout: time.Minute,
+	})
+	cmd.Start()
+
+	time.Sleep(100 * time.Millisecond)
+
+	start = time.Now()
+	if err := cmd.Terminate(ctx); err == nil {
+		t.Error("failed to report killing after the context is over")
+	}
+
+	if exist := time.Since(start); exist > timeout {
+		t.Error("failed to bound the stop timeout by the context. Got duration", exist)
+	}
+}
+
+func TestCoreCmd_Env(t *testing.T) {
+	expected := "value\n"
+
+	cmd, err := NewCoreCmd(&testParameter{
+		command: "sh",
+		args:    []string{"-c", "echo $TEST_ENV_VALUE; sleep 0.1"},
+		env:     []string{"TEST_ENV_VALUE=value"},
+	})
+	if cmd == nil || err != nil {
+		t.Error("failed to create command with", err)
+		return
+	}
+
+	if err := cmd.Start(); err != nil {
+		t.Error("failed to start command with", err)
+		return
+	}
+
+	if exist := <-cmd.StdOut(); exist != expected {
+		t.Error("failed to pass an environment. Got '", exist, "', but expected is '", expected, "'")
+	}
+
+	<-cmd.Wait()
+}
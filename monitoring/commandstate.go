@@ -3,18 +3,60 @@ package monitoring
 import (
 	"context"
 	"io"
+	"os"
+	"os/exec"
+	"strconv"
+	"sync"
 	"sync/atomic"
+	"time"
 
 	"github.com/7phs/tools/common"
 	"github.com/pkg/errors"
 )
 
+// Variables added to the environment of every instance.
+const (
+	EnvInstance = "MONITORING_INSTANCE"
+	EnvRun      = "MONITORING_RUN"
+	EnvPort     = "MONITORING_PORT"
+)
+
 type commandState struct {
 	CoreCmd
 
+	// runLock guards a command replaced by every run against readers of other goroutines
+	runLock sync.RWMutex
+	// control orders restarts of monitoring and commands of the instance
+	control sync.Mutex
+	// monitored is closed when monitoringProcess of the instance has returned
+	monitored chan struct{}
+	// leave is closed by StopInstance to make monitoringProcess of the instance return
+	leave chan struct{}
+	// removed is closed by Scale to stop checks of a removed instance
+	removed chan interface{}
+
 	stdErrIsOk     bool
 	checkStartLine func(string) bool
+	stdoutSink     OutputSink
+	stderrSink     OutputSink
+	readiness      *ReadinessConfig
+	index          int
 	runCount       int32
+	restartAttempt int32
+	unhealthy      int32
+	recycled       int32
+	held           int32
+	finished       int32
+	startLatency   int64
+
+	exits  exitHistory
+	events *eventBus
+	stats  atomic.Value
+
+	ports *portAllocator
+	port  int
+
+	templates *templateConfig
 }
 
 func CommandState(parameter MonitoringParameter) (*commandState, error) {
@@ -25,11 +67,23 @@ func CommandState(parameter MonitoringParameter) (*commandState, error) {
 }
 
 func (o *commandState) Init(parameter MonitoringParameter) error {
+	o.runLock.Lock()
 	_, err := o.init(parameter).prepare()
+	o.runLock.Unlock()
 
 	o.checkStartLine = parameter.CheckStartLine()
 	o.stdErrIsOk = parameter.StdErrIsOk()
 
+	o.templates = newTemplateConfig(parameter)
+
+	o.readiness = nil
+	if readiness, ok := parameter.(ReadinessParameter); ok {
+		o.readiness = readiness.Readiness()
+	}
+	if o.stdoutSink == nil || o.stderrSink == nil {
+		o.stdoutSink, o.stderrSink = DiscardSink(), DiscardSink()
+	}
+
 	return err
 }
 
@@ -37,13 +91,137 @@ func (o *commandState) RunCount() int32 {
 	return atomic.LoadInt32(&o.runCount)
 }
 
+// ExitHistory returns last records about how runs of the command ended.
+func (o *commandState) ExitHistory() []ExitRecord {
+	return o.exits.Records()
+}
+
+func (o *commandState) recordExit(cmd *exec.Cmd, wait <-chan bool, record ExitRecord) {
+	<-wait
+
+	o.exits.add(newExitRecord(record, cmd.ProcessState))
+}
+
+func (o *commandState) restartDelay(policy RestartPolicy) time.Duration {
+	if policy.Healthy(time.Since(o.StartTime())) {
+		o.restartAttempt = 0
+	}
+	o.restartAttempt++
+
+	return policy.Delay(o.restartAttempt)
+}
+
+func (o *commandState) emit(eventType EventType, err error) {
+	o.events.emit(Event{
+		Type:     eventType,
+		Instance: o.index,
+		Pid:      o.Pid(),
+		Err:      err,
+	})
+}
+
+// exitRecord is valid after the command has finished.
+func (o *commandState) exitRecord() *ExitRecord {
+	record := newExitRecord(ExitRecord{
+		Instance:  o.index,
+		Run:       o.RunCount(),
+		StartTime: o.StartTime(),
+	}, o.cmd.ProcessState)
+
+	return &record
+}
+
+func (o *commandState) markUnhealthy() {
+	atomic.StoreInt32(&o.unhealthy, 1)
+}
+
+// IsUnhealthy reports the current run was killed by a failed health check or exceeded resource limits.
+func (o *commandState) IsUnhealthy() bool {
+	return atomic.LoadInt32(&o.unhealthy) != 0
+}
+
+func (o *commandState) markRecycled() {
+	atomic.StoreInt32(&o.recycled, 1)
+}
+
+// recycleRuns restarts the instance after the current run as a fresh one.
+// It is called by monitoringProcess between runs the same as restartDelay.
+func (o *commandState) recycleRuns() {
+	o.markRecycled()
+	o.restartAttempt = 0
+}
+
+// isRecycled reports the current run was stopped over its lifetime.
+func (o *commandState) isRecycled() bool {
+	return atomic.LoadInt32(&o.recycled) != 0
+}
+
+// hold keeps monitoring from restarting the instance.
+func (o *commandState) hold() {
+	atomic.StoreInt32(&o.held, 1)
+}
+
+func (o *commandState) release() {
+	atomic.StoreInt32(&o.held, 0)
+}
+
+func (o *commandState) isHeld() bool {
+	return atomic.LoadInt32(&o.held) != 0
+}
+
+// leaveMonitoring makes monitoringProcess of the instance return, it cancels a pending restart too.
+func (o *commandState) leaveMonitoring() {
+	select {
+	case <-o.leave:
+	default:
+		close(o.leave)
+	}
+}
+
+// controlRun acts on the run of the instance under the control lock. It is skipped when the instance
+// is stopped, the run has ended or a restart has replaced it meanwhile.
+func (o *commandState) controlRun(run int32, action func()) {
+	o.control.Lock()
+	defer o.control.Unlock()
+
+	if o.isHeld() || o.isFinished() || o.RunCount() != run {
+		return
+	}
+
+	action()
+}
+
 func (o *commandState) Run(ctx context.Context, wait chan<- error) {
+	atomic.StoreInt32(&o.unhealthy, 0)
+	atomic.StoreInt32(&o.recycled, 0)
+	o.emit(EventStarting, nil)
+
+	if o.readiness != nil {
+		o.finishRun(o.waitReadiness(ctx), wait)
+		return
+	}
+
 	firstLine, err := o.startCommand(ctx)
 
 	if err == nil && !o.checkLine(firstLine) {
 		err = o.waitStartLine(ctx)
 	}
 
+	// keep consuming an output till the end of the run, the command stalls on a full pipe otherwise
+	go drainStream(o.StdOut(), o.index, o.stdoutSink)
+	go drainStream(o.StdErr(), o.index, o.stderrSink)
+
+	o.finishRun(err, wait)
+}
+
+func (o *commandState) finishRun(err error, wait chan<- error) {
+	if err == nil {
+		atomic.StoreInt64(&o.startLatency, int64(time.Since(o.StartTime())))
+		o.emit(EventStartLineMatched, nil)
+	} else {
+		o.emit(EventError, err)
+	}
+
 	wait <- err
 }
 
@@ -55,17 +233,125 @@ func (o *commandState) checkLine(line string) bool {
 	return o.checkStartLine(line)
 }
 
+// Port returns a port assigned to the current run or 0 without PortParameter.
+func (o *commandState) Port() int {
+	return o.port
+}
+
+func (o *commandState) instance() Instance {
+	return Instance{
+		Index: o.index,
+		Pid:   o.Pid(),
+		Port:  o.port,
+	}
+}
+
+// instanceEnv adds variables of the instance to the environment of the next run.
+func (o *commandState) instanceEnv() {
+	if o.cmd.Env == nil {
+		o.cmd.Env = os.Environ()
+	}
+
+	o.cmd.Env = append(o.cmd.Env,
+		EnvInstance+"="+strconv.Itoa(o.index),
+		EnvRun+"="+strconv.Itoa(int(o.RunCount()+1)),
+	)
+
+	if o.port != 0 {
+		o.cmd.Env = append(o.cmd.Env, EnvPort+"="+strconv.Itoa(o.port))
+	}
+}
+
+// allocatePort assigns a free port to the next run and places it into arguments and the environment.
+func (o *commandState) allocatePort() (err error) {
+	if o.ports == nil {
+		return nil
+	}
+
+	if o.port, err = o.ports.allocate(o.index); err != nil {
+		return err
+	}
+
+	expandPort(o.cmd.Args[1:], o.port)
+	expandPort(o.cmd.Env, o.port)
+
+	return nil
+}
+
+func (o *commandState) startProcess() error {
+	if err := o.launch(); err != nil {
+		return err
+	}
+
+	go o.recordExit(o.cmd, o.Wait(), ExitRecord{
+		Instance:  o.index,
+		Run:       atomic.AddInt32(&o.runCount, 1),
+		StartTime: o.StartTime(),
+	})
+
+	o.emit(EventStarted, nil)
+
+	return nil
+}
+
+func (o *commandState) launch() error {
+	o.runLock.Lock()
+	defer o.runLock.Unlock()
+
+	if err := o.allocatePort(); err != nil {
+		return err
+	}
+	if err := o.expandTemplates(); err != nil {
+		return err
+	}
+	o.instanceEnv()
+
+	return errors.Wrapf(o.Start(), "failed to start command for monitoring")
+}
+
+// status is safe to call while the command is restarting.
+func (o *commandState) status() InstanceStatus {
+	o.runLock.RLock()
+	defer o.runLock.RUnlock()
+
+	status := InstanceStatus{
+		Index:     o.index,
+		Pid:       o.Pid(),
+		Port:      o.port,
+		Runs:      o.RunCount(),
+		StartTime: o.StartTime(),
+		Unhealthy: o.IsUnhealthy(),
+		Stopped:   o.isHeld(),
+	}
+
+	status.Exited = o.isFinished()
+
+	return status
+}
+
+// StartLatency returns a duration from a start of the last ready run till it has passed a start check.
+func (o *commandState) StartLatency() time.Duration {
+	return time.Duration(atomic.LoadInt64(&o.startLatency))
+}
+
+// isFinished reports the current run has ended by any way, IsExited is false for killed commands.
+// It is safe to call concurrently with the run unlike IsExited.
+func (o *commandState) isFinished() bool {
+	select {
+	case <-o.Wait():
+		return true
+	default:
+		return false
+	}
+}
+
 func (o *commandState) startCommand(ctx context.Context) (line string, err error) {
 	var (
 		ok bool
 	)
 
-	err = o.Start()
-	if err != nil {
-		err = errors.Wrapf(err, "failed to start command for monitoring")
-	} else {
-		atomic.AddInt32(&o.runCount, 1)
-
+	err = o.startProcess()
+	if err == nil {
 		select {
 		case <-common.DeadLineTimer(ctx.Deadline()).C:
 			err = errors.New("timeout error")
@@ -76,6 +362,8 @@ func (o *commandState) startCommand(ctx context.Context) (line string, err error
 				if errMsg := common.ReadAll(o.StdErr()); len(errMsg) > 0 {
 					err = errors.New(errMsg)
 				}
+			} else {
+				o.stdoutSink.WriteLine(o.index, line)
 			}
 
 		case line, ok = <-o.StdErr():
@@ -83,8 +371,12 @@ func (o *commandState) startCommand(ctx context.Context) (line string, err error
 				if errMsg := common.ReadAll(o.StdErr()); len(errMsg) > 0 {
 					err = errors.New(line + errMsg)
 				}
-			} else if !o.stdErrIsOk {
-				err = errors.New(line)
+			} else {
+				o.stderrSink.WriteLine(o.index, line)
+
+				if !o.stdErrIsOk {
+					err = errors.New(line)
+				}
 			}
 
 		case <-ctx.Done():
@@ -114,6 +406,7 @@ func (o *commandState) waitStartLine(ctx context.Context) (err error) {
 					err = errors.New(errMsg)
 				}
 			} else {
+				o.stdoutSink.WriteLine(o.index, line)
 				foundLine = o.checkLine(line)
 			}
 
@@ -125,6 +418,7 @@ func (o *commandState) waitStartLine(ctx context.Context) (err error) {
 					err = io.EOF
 				}
 			} else {
+				o.stderrSink.WriteLine(o.index, line)
 				foundLine = o.checkLine(line)
 			}
 
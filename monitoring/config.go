@@ -0,0 +1,313 @@
+package monitoring
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v2"
+
+	"github.com/7phs/tools/common"
+)
+
+// Running modes of a config file, a count of repeats is also valid.
+const (
+	ModeInfinity = "infinity"
+	ModeOnce     = "once"
+)
+
+type decoder func(data []byte, v interface{}) error
+
+// decoders by an extension of a config file
+var decoders = map[string]decoder{
+	".json": json.Unmarshal,
+	".yaml": yaml.Unmarshal,
+	".yml":  yaml.Unmarshal,
+	".toml": toml.Unmarshal,
+}
+
+// ParameterConfig describes a command to monitor in a config file.
+// RunningMode is ModeInfinity (by default), ModeOnce or a count of repeats.
+// StartLine is a regular expression of a line reporting the command has started.
+// Restart is a name of RestartMode, always by default.
+// MaxLifetime is a duration like "1h30m" of LifetimeConfig.
+// StopSignal is a name like "TERM" or "SIGTERM", StopTimeout is a duration, see StopParameter.
+type ParameterConfig struct {
+	WorkDir        string            `json:"workdir" yaml:"workdir" toml:"workdir"`
+	Command        string            `json:"command" yaml:"command" toml:"command"`
+	Args           []string          `json:"args" yaml:"args" toml:"args"`
+	Env            map[string]string `json:"env" yaml:"env" toml:"env"`
+	ParallelCount  int32             `json:"parallel_count" yaml:"parallel_count" toml:"parallel_count"`
+	RunningMode    string            `json:"running_mode" yaml:"running_mode" toml:"running_mode"`
+	StartLine      string            `json:"start_line" yaml:"start_line" toml:"start_line"`
+	StdErrIsOk     bool              `json:"stderr_is_ok" yaml:"stderr_is_ok" toml:"stderr_is_ok"`
+	Restart        string            `json:"restart" yaml:"restart" toml:"restart"`
+	SuccessCodes   []int             `json:"success_codes" yaml:"success_codes" toml:"success_codes"`
+	MaxLifetime    string            `json:"max_lifetime" yaml:"max_lifetime" toml:"max_lifetime"`
+	LifetimeJitter float64           `json:"lifetime_jitter" yaml:"lifetime_jitter" toml:"lifetime_jitter"`
+	MaxRuns        int32             `json:"max_runs" yaml:"max_runs" toml:"max_runs"`
+	StopSignal     string            `json:"stop_signal" yaml:"stop_signal" toml:"stop_signal"`
+	StopTimeout    string            `json:"stop_timeout" yaml:"stop_timeout" toml:"stop_timeout"`
+	ProcessGroup   bool              `json:"process_group" yaml:"process_group" toml:"process_group"`
+}
+
+type fileParameter struct {
+	config      ParameterConfig
+	env         []string
+	runningMode int32
+	startLine   *regexp.Regexp
+	restartMode RestartMode
+	lifetime    *LifetimeConfig
+	stopSignal  os.Signal
+	stopTimeout time.Duration
+}
+
+// LoadParameter reads a parameter from a YAML, JSON or TOML file chosen by an extension.
+func LoadParameter(path string) (*fileParameter, error) {
+	var config ParameterConfig
+
+	if err := ReadParameterConfig(path, &config); err != nil {
+		return nil, err
+	}
+
+	parameter, err := NewFileParameter(config)
+
+	return parameter, errors.Wrapf(err, "failed to load %s", path)
+}
+
+// ReadParameterConfig decodes a file over config without a validation, so fields could be changed before.
+func ReadParameterConfig(path string, config *ParameterConfig) error {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return errors.Wrapf(err, "failed to read %s", path)
+	}
+
+	return errors.Wrapf(decodeConfig(data, filepath.Ext(path), config), "failed to load %s", path)
+}
+
+// ParseParameter decodes a parameter of the format given as an extension of a file (".yaml", ".json", etc.).
+func ParseParameter(data []byte, ext string) (*fileParameter, error) {
+	var config ParameterConfig
+
+	if err := decodeConfig(data, ext, &config); err != nil {
+		return nil, err
+	}
+
+	return NewFileParameter(config)
+}
+
+func decodeConfig(data []byte, ext string, config *ParameterConfig) error {
+	decode, ok := decoders[strings.ToLower(ext)]
+	if !ok {
+		return errors.Errorf("unsupported format '%s' of a config", ext)
+	}
+
+	return errors.Wrap(decode(data, config), "failed to decode a config")
+}
+
+// NewFileParameter validates a config and reports all found errors at once.
+func NewFileParameter(config ParameterConfig) (*fileParameter, error) {
+	var (
+		parameter = &fileParameter{config: config}
+		errs      []error
+	)
+
+	if config.Command == "" {
+		errs = append(errs, errors.New("command is empty"))
+	}
+
+	if config.WorkDir != "" {
+		if info, err := os.Stat(config.WorkDir); err != nil {
+			errs = append(errs, errors.Wrap(err, "invalid workdir"))
+		} else if !info.IsDir() {
+			errs = append(errs, errors.Errorf("workdir %s is not a directory", config.WorkDir))
+		}
+	}
+
+	if config.ParallelCount < 0 {
+		errs = append(errs, errors.Errorf("parallel_count %d is negative", config.ParallelCount))
+	}
+
+	var err error
+
+	if parameter.runningMode, err = parseRunningMode(config.RunningMode); err != nil {
+		errs = append(errs, err)
+	}
+
+	if config.StartLine != "" {
+		if parameter.startLine, err = regexp.Compile(config.StartLine); err != nil {
+			errs = append(errs, errors.Wrap(err, "invalid start_line"))
+		}
+	}
+
+	if config.Restart != "" {
+		if parameter.restartMode, err = ParseRestartMode(config.Restart); err != nil {
+			errs = append(errs, errors.Wrap(err, "invalid restart"))
+		}
+	}
+
+	if parameter.lifetime, err = parseLifetime(config); err != nil {
+		errs = append(errs, err)
+	}
+
+	if config.StopSignal != "" {
+		if parameter.stopSignal, err = parseSignal(config.StopSignal); err != nil {
+			errs = append(errs, errors.Wrap(err, "invalid stop_signal"))
+		}
+	}
+
+	if config.StopTimeout != "" {
+		if parameter.stopTimeout, err = time.ParseDuration(config.StopTimeout); err != nil {
+			errs = append(errs, errors.Wrap(err, "invalid stop_timeout"))
+		} else if parameter.stopTimeout < 0 {
+			errs = append(errs, errors.Errorf("stop_timeout %s is negative", config.StopTimeout))
+		}
+	}
+
+	for key, value := range config.Env {
+		if key == "" || strings.Contains(key, "=") {
+			errs = append(errs, errors.Errorf("invalid name '%s' of env", key))
+			continue
+		}
+
+		parameter.env = append(parameter.env, key+"="+value)
+	}
+	sort.Strings(parameter.env)
+
+	if err = common.SeveralErrors("invalid parameter", errs...); err != nil {
+		return nil, err
+	}
+
+	return parameter, nil
+}
+
+func parseRunningMode(mode string) (int32, error) {
+	switch strings.ToLower(mode) {
+	case "", ModeInfinity:
+		return RepeatInfinity, nil
+
+	case ModeOnce:
+		return RunOnce, nil
+	}
+
+	count, err := strconv.ParseInt(mode, 10, 32)
+	if err != nil || count <= 0 {
+		return 0, errors.Errorf("running_mode '%s' is not %s, %s or a positive count", mode, ModeInfinity, ModeOnce)
+	}
+
+	return int32(count), nil
+}
+
+// parseSignal parses a name of a signal with or without the SIG prefix.
+func parseSignal(name string) (os.Signal, error) {
+	sig, ok := signalNames[strings.TrimPrefix(strings.ToUpper(name), "SIG")]
+	if !ok {
+		return nil, errors.Errorf("unsupported signal '%s'", name)
+	}
+
+	return sig, nil
+}
+
+func parseLifetime(config ParameterConfig) (*LifetimeConfig, error) {
+	var (
+		lifetime LifetimeConfig
+		errs     []error
+		err      error
+	)
+
+	if config.MaxLifetime != "" {
+		if lifetime.MaxLifetime, err = time.ParseDuration(config.MaxLifetime); err != nil {
+			errs = append(errs, errors.Wrap(err, "invalid max_lifetime"))
+		} else if lifetime.MaxLifetime < 0 {
+			errs = append(errs, errors.Errorf("max_lifetime %s is negative", config.MaxLifetime))
+		}
+	}
+
+	if config.LifetimeJitter < 0 || config.LifetimeJitter > 1 {
+		errs = append(errs, errors.Errorf("lifetime_jitter %v is out of [0, 1]", config.LifetimeJitter))
+	}
+	lifetime.Jitter = config.LifetimeJitter
+
+	if config.MaxRuns < 0 {
+		errs = append(errs, errors.Errorf("max_runs %d is negative", config.MaxRuns))
+	}
+	lifetime.MaxRuns = config.MaxRuns
+
+	if len(errs) > 0 {
+		return nil, common.SeveralErrors("invalid lifetime", errs...)
+	}
+
+	if lifetime.MaxLifetime == 0 && lifetime.MaxRuns == 0 {
+		return nil, nil
+	}
+
+	return &lifetime, nil
+}
+
+func (o *fileParameter) WorkDir() string {
+	return o.config.WorkDir
+}
+
+func (o *fileParameter) Command() string {
+	return o.config.Command
+}
+
+func (o *fileParameter) ToArgs() []string {
+	return o.config.Args
+}
+
+func (o *fileParameter) Env() []string {
+	return o.env
+}
+
+func (o *fileParameter) RunningMode() int32 {
+	return o.runningMode
+}
+
+func (o *fileParameter) ParallelCount() int32 {
+	return o.config.ParallelCount
+}
+
+func (o *fileParameter) StdErrIsOk() bool {
+	return o.config.StdErrIsOk
+}
+
+func (o *fileParameter) CheckStartLine() func(string) bool {
+	if o.startLine == nil {
+		return nil
+	}
+
+	return o.startLine.MatchString
+}
+
+func (o *fileParameter) RestartMode() RestartMode {
+	return o.restartMode
+}
+
+func (o *fileParameter) SuccessExitCodes() []int {
+	return o.config.SuccessCodes
+}
+
+func (o *fileParameter) Lifetime() *LifetimeConfig {
+	return o.lifetime
+}
+
+func (o *fileParameter) StopSignal() os.Signal {
+	return o.stopSignal
+}
+
+func (o *fileParameter) StopTimeout() time.Duration {
+	return o.stopTimeout
+}
+
+func (o *fileParameter) ProcessGroup() bool {
+	return o.config.ProcessGroup
+}
@@ -0,0 +1,237 @@
+package monitoring
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestParseParameter(t *testing.T) {
+	testSuites := []struct {
+		ext  string
+		data string
+	}{
+		{
+			ext: ".yaml",
+			data: `
+command: sh
+args: ["-c", "echo started"]
+env:
+  B: "2"
+  A: "1"
+parallel_count: 2
+running_mode: "3"
+start_line: ^start
+stderr_is_ok: true
+restart: on-failure
+success_codes: [2]
+max_lifetime: 1h30m
+lifetime_jitter: 0.1
+max_runs: 5
+stop_signal: SIGTERM
+stop_timeout: 5s
+process_group: true
+`,
+		},
+		{
+			ext: ".json",
+			data: `{
+	"command": "sh",
+	"args": ["-c", "echo started"],
+	"env": {"B": "2", "A": "1"},
+	"parallel_count": 2,
+	"running_mode": "3",
+	"start_line": "^start",
+	"stderr_is_ok": true,
+	"restart": "on-failure",
+	"success_codes": [2],
+	"max_lifetime": "1h30m",
+	"lifetime_jitter": 0.1,
+	"max_runs": 5,
+	"stop_signal": "SIGTERM",
+	"stop_timeout": "5s",
+	"process_group": true
+}`,
+		},
+		{
+			ext: ".toml",
+			data: `
+command = "sh"
+args = ["-c", "echo started"]
+parallel_count = 2
+running_mode = "3"
+start_line = "^start"
+stderr_is_ok = true
+restart = "on-failure"
+success_codes = [2]
+max_lifetime = "1h30m"
+lifetime_jitter = 0.1
+max_runs = 5
+stop_signal = "SIGTERM"
+stop_timeout = "5s"
+process_group = true
+
+[env]
+B = "2"
+A = "1"
+`,
+		},
+	}
+
+	for _, test := range testSuites {
+		parameter, err := ParseParameter([]byte(test.data), test.ext)
+		if err != nil {
+			t.Error(test.ext, ": failed to parse a parameter with", err)
+			continue
+		}
+
+		if exist := parameter.Command(); exist != "sh" {
+			t.Error(test.ext, ": failed to parse a command. Got", exist, ", but expected is", "sh")
+		}
+
+		if exist, expected := parameter.ToArgs(), []string{"-c", "echo started"}; !reflect.DeepEqual(exist, expected) {
+			t.Error(test.ext, ": failed to parse args. Got", exist, ", but expected is", expected)
+		}
+
+		if exist, expected := parameter.Env(), []string{"A=1", "B=2"}; !reflect.DeepEqual(exist, expected) {
+			t.Error(test.ext, ": failed to parse env. Got", exist, ", but expected is", expected)
+		}
+
+		if exist := parameter.ParallelCount(); exist != 2 {
+			t.Error(test.ext, ": failed to parse a parallel count. Got", exist, ", but expected is", 2)
+		}
+
+		if exist := parameter.RunningMode(); exist != 3 {
+			t.Error(test.ext, ": failed to parse a running mode. Got", exist, ", but expected is", 3)
+		}
+
+		if !parameter.StdErrIsOk() {
+			t.Error(test.ext, ": failed to parse a stderr policy")
+		}
+
+		if check := parameter.CheckStartLine(); check == nil || !check("started\n") || check("not started\n") {
+			t.Error(test.ext, ": failed to parse a start line")
+		}
+
+		if exist := parameter.RestartMode(); exist != RestartOnFailure {
+			t.Error(test.ext, ": failed to parse a restart mode. Got", exist, ", but expected is", RestartOnFailure)
+		}
+
+		if exist, expected := parameter.SuccessExitCodes(), []int{2}; !reflect.DeepEqual(exist, expected) {
+			t.Error(test.ext, ": failed to parse success codes. Got", exist, ", but expected is", expected)
+		}
+
+		if exist, expected := parameter.Lifetime(), (&LifetimeConfig{MaxLifetime: 90 * time.Minute, Jitter: 0.1, MaxRuns: 5}); !reflect.DeepEqual(exist, expected) {
+			t.Error(test.ext, ": failed to parse a lifetime. Got", exist, ", but expected is", expected)
+		}
+
+		if exist := parameter.StopSignal(); exist != syscall.SIGTERM {
+			t.Error(test.ext, ": failed to parse a stop signal. Got", exist, ", but expected is", syscall.SIGTERM)
+		}
+
+		if exist := parameter.StopTimeout(); exist != 5*time.Second {
+			t.Error(test.ext, ": failed to parse a stop timeout. Got", exist, ", but expected is", 5*time.Second)
+		}
+
+		if !parameter.ProcessGroup() {
+			t.Error(test.ext, ": failed to parse a process group")
+		}
+	}
+
+	if _, err := ParseParameter([]byte("command: sh"), ".ini"); err == nil {
+		t.Error("failed to catch an error of an unsupported format")
+	}
+
+	if _, err := ParseParameter([]byte("{command"), ".json"); err == nil {
+		t.Error("failed to catch an error of a broken config")
+	}
+}
+
+func TestNewFileParameter(t *testing.T) {
+	parameter, err := NewFileParameter(ParameterConfig{Command: "sh"})
+	if err != nil {
+		t.Error("failed to create a parameter with", err)
+		return
+	}
+
+	if exist := parameter.RunningMode(); exist != RepeatInfinity {
+		t.Error("failed to use a default running mode. Got", exist, ", but expected is", RepeatInfinity)
+	}
+
+	if check := parameter.CheckStartLine(); check != nil {
+		t.Error("failed to skip a check of a start line")
+	}
+
+	if lifetime := parameter.Lifetime(); lifetime != nil {
+		t.Error("failed to skip a lifetime. Got", lifetime)
+	}
+
+	if sig := parameter.StopSignal(); sig != nil {
+		t.Error("failed to skip a stop signal. Got", sig)
+	}
+
+	if parameter, _ = NewFileParameter(ParameterConfig{Command: "sh", StopSignal: "int"}); parameter.StopSignal() != syscall.SIGINT {
+		t.Error("failed to parse a stop signal. Got", parameter.StopSignal(), ", but expected is", syscall.SIGINT)
+	}
+
+	if parameter, _ = NewFileParameter(ParameterConfig{Command: "sh", RunningMode: ModeOnce}); parameter.RunningMode() != RunOnce {
+		t.Error("failed to parse a running mode. Got", parameter.RunningMode(), ", but expected is", RunOnce)
+	}
+
+	_, err = NewFileParameter(ParameterConfig{
+		WorkDir:       "/not/existing/dir",
+		ParallelCount: -1,
+		RunningMode:   "sometimes",
+		StartLine:     "[start",
+		Env:           map[string]string{"A=B": "1"},
+		Restart:       "never",
+		MaxLifetime:   "forever",
+		MaxRuns:       -1,
+		StopSignal:    "SIGNONE",
+		StopTimeout:   "-1s",
+	})
+	if err == nil {
+		t.Error("failed to catch errors of an invalid config")
+		return
+	}
+
+	for _, expected := range []string{"command", "workdir", "parallel_count", "running_mode", "start_line", "env", "restart", "max_lifetime", "max_runs", "stop_signal", "stop_timeout"} {
+		if !strings.Contains(err.Error(), expected) {
+			t.Error("failed to report an error of", expected, ". Got", err)
+		}
+	}
+}
+
+func TestLoadParameter(t *testing.T) {
+	dir, err := ioutil.TempDir("", "monitoring-config")
+	if err != nil {
+		t.Error("failed to create a temp dir with", err)
+		return
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "command.yml")
+	if err = ioutil.WriteFile(path, []byte("command: sh\nworkdir: "+dir+"\n"), 0644); err != nil {
+		t.Error("failed to write a config with", err)
+		return
+	}
+
+	parameter, err := LoadParameter(path)
+	if err != nil {
+		t.Error("failed to load a parameter with", err)
+		return
+	}
+
+	if exist := parameter.WorkDir(); exist != dir {
+		t.Error("failed to load a workdir. Got", exist, ", but expected is", dir)
+	}
+
+	if _, err = LoadParameter(filepath.Join(dir, "unknown.yml")); err == nil {
+		t.Error("failed to catch an error of a missing file")
+	}
+}
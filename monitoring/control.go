@@ -0,0 +1,342 @@
+package monitoring
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	followBufferSize = 100
+)
+
+type controlError struct {
+	Error string `json:"error"`
+}
+
+type controlHandler struct {
+	*http.ServeMux
+
+	monitoring *Monitoring
+}
+
+// ControlHandler exposes a Monitoring over HTTP:
+//
+//	GET  /status                   - Status
+//	POST /start, /stop, /kill      - commands of monitoring, respond with Status
+//	POST /restart?instance=N       - restart an instance by RestartInstance
+//	GET  /tail?lines=N[&follow=1]  - last lines of an output, follow streams new ones as JSON lines
+//	GET  /metrics                  - metrics in the Prometheus text format by MetricsCollector
+func ControlHandler(monitoring *Monitoring) http.Handler {
+	handler := &controlHandler{
+		ServeMux:   http.NewServeMux(),
+		monitoring: monitoring,
+	}
+
+	handler.handle("/status", http.MethodGet, handler.status)
+	handler.handle("/start", http.MethodPost, handler.start)
+	handler.handle("/stop", http.MethodPost, handler.stop)
+	handler.handle("/kill", http.MethodPost, handler.kill)
+	handler.handle("/restart", http.MethodPost, handler.restart)
+	handler.handle("/tail", http.MethodGet, handler.tail)
+	handler.Handle("/metrics", MetricsHandler(NewMetricsCollector(monitoring, nil)))
+
+	return handler
+}
+
+func (o *controlHandler) handle(pattern, method string, handler http.HandlerFunc) {
+	o.HandleFunc(pattern, func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != method {
+			w.Header().Set("Allow", method)
+			writeJSON(w, http.StatusMethodNotAllowed, controlError{Error: "method " + r.Method + " is not allowed"})
+			return
+		}
+
+		handler(w, r)
+	})
+}
+
+func (o *controlHandler) status(w http.ResponseWriter, _ *http.Request) {
+	writeJSON(w, http.StatusOK, o.monitoring.Status())
+}
+
+// commands of monitoring outlive a request, it isn't canceled by a disconnected client
+func (o *controlHandler) start(w http.ResponseWriter, _ *http.Request) {
+	// a repeated start is reported as an error of monitoring
+	if o.monitoring.isRunning() {
+		writeJSON(w, http.StatusConflict, controlError{Error: "monitoring is already started"})
+		return
+	}
+
+	o.monitoring.Start(context.Background())
+
+	status := http.StatusOK
+	if !o.monitoring.isRunning() {
+		status = http.StatusConflict
+	}
+
+	writeJSON(w, status, o.monitoring.Status())
+}
+
+func (o *controlHandler) stop(w http.ResponseWriter, _ *http.Request) {
+	o.monitoring.Stop(context.Background())
+
+	writeJSON(w, http.StatusOK, o.monitoring.Status())
+}
+
+func (o *controlHandler) kill(w http.ResponseWriter, _ *http.Request) {
+	o.monitoring.Kill(context.Background())
+
+	writeJSON(w, http.StatusOK, o.monitoring.Status())
+}
+
+// a restart canceled by a disconnected client would leave the instance stopped
+func (o *controlHandler) restart(w http.ResponseWriter, r *http.Request) {
+	index, err := strconv.Atoi(r.URL.Query().Get("instance"))
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, controlError{Error: "invalid instance: " + err.Error()})
+		return
+	}
+
+	if err = o.monitoring.RestartInstance(context.Background(), index); err != nil {
+		writeJSON(w, http.StatusConflict, controlError{Error: err.Error()})
+		return
+	}
+
+	writeJSON(w, http.StatusOK, o.monitoring.Status())
+}
+
+func (o *controlHandler) tail(w http.ResponseWriter, r *http.Request) {
+	var (
+		query = r.URL.Query()
+		count int
+		err   error
+	)
+
+	if lines := query.Get("lines"); lines != "" {
+		if count, err = strconv.Atoi(lines); err != nil {
+			writeJSON(w, http.StatusBadRequest, controlError{Error: "invalid lines: " + err.Error()})
+			return
+		}
+	}
+
+	follow, _ := strconv.ParseBool(query.Get("follow"))
+	if !follow {
+		writeJSON(w, http.StatusOK, o.monitoring.Tail(count))
+		return
+	}
+
+	// subscribe before taking a tail to don't lose lines between them
+	lines, unsubscribe := o.monitoring.FollowOutput(followBufferSize)
+	defer unsubscribe()
+
+	w.Header().Set("Content-Type", "application/x-ndjson")
+	w.WriteHeader(http.StatusOK)
+
+	flusher, _ := w.(http.Flusher)
+	encoder := json.NewEncoder(w)
+
+	for _, line := range o.monitoring.Tail(count) {
+		encoder.Encode(line)
+	}
+
+	for {
+		if flusher != nil {
+			flusher.Flush()
+		}
+
+		select {
+		case line, ok := <-lines:
+			if !ok {
+				return
+			}
+
+			if err := encoder.Encode(line); err != nil {
+				return
+			}
+
+		case <-r.Context().Done():
+			return
+		}
+	}
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+
+	json.NewEncoder(w).Encode(v)
+}
+
+type controlServer struct {
+	server   *http.Server
+	listener net.Listener
+	socket   string
+}
+
+// ListenControl serves ControlHandler on a "unix" socket or a "tcp" address of a loopback interface,
+// the API has no authentication. A stale socket file is replaced.
+func ListenControl(network, address string, monitoring *Monitoring) (*controlServer, error) {
+	server := &controlServer{
+		server: &http.Server{
+			Handler: ControlHandler(monitoring),
+		},
+	}
+
+	switch network {
+	case "unix":
+		if err := removeStaleSocket(address); err != nil {
+			return nil, err
+		}
+		server.socket = address
+
+	case "tcp", "tcp4", "tcp6":
+		if err := checkLoopback(address); err != nil {
+			return nil, err
+		}
+
+	default:
+		return nil, errors.Errorf("failed to listen an unsupported network '%s'", network)
+	}
+
+	listener, err := net.Listen(network, address)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to listen %s %s", network, address)
+	}
+	server.listener = listener
+
+	go server.server.Serve(listener)
+
+	return server, nil
+}
+
+func (o *controlServer) Addr() net.Addr {
+	return o.listener.Addr()
+}
+
+// Close waits active requests till ctx is over, following of a tail is broken by ctx only.
+func (o *controlServer) Close(ctx context.Context) error {
+	err := o.server.Shutdown(ctx)
+	if err != nil {
+		o.server.Close()
+	}
+
+	if o.socket != "" {
+		os.Remove(o.socket)
+	}
+
+	return errors.Wrap(err, "failed to close a control server")
+}
+
+func checkLoopback(address string) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return errors.Wrapf(err, "invalid address %s", address)
+	}
+
+	if host == "localhost" {
+		return nil
+	}
+
+	if ip := net.ParseIP(host); ip == nil || !ip.IsLoopback() {
+		return errors.Errorf("failed to listen %s - only a loopback interface is allowed", address)
+	}
+
+	return nil
+}
+
+// removeStaleSocket removes a socket file nobody listens to.
+func removeStaleSocket(path string) error {
+	info, err := os.Stat(path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+
+	if err != nil || info.Mode()&os.ModeSocket == 0 {
+		return errors.Errorf("failed to listen %s - not a socket", path)
+	}
+
+	if conn, err := net.Dial("unix", path); err == nil {
+		conn.Close()
+		return errors.Errorf("failed to listen %s - already in use", path)
+	}
+
+	return errors.Wrapf(os.Remove(path), "failed to remove a stale socket %s", path)
+}
+
+type controlClient struct {
+	client *http.Client
+}
+
+// DialControl returns a client of ListenControl, a connection is established by every request.
+func DialControl(network, address string) *controlClient {
+	return &controlClient{
+		client: &http.Client{
+			Transport: &http.Transport{
+				DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+					var dialer net.Dialer
+
+					return dialer.DialContext(ctx, network, address)
+				},
+			},
+		},
+	}
+}
+
+func (o *controlClient) Status(ctx context.Context) (status Status, err error) {
+	err = o.request(ctx, http.MethodGet, "/status", &status)
+	return
+}
+
+func (o *controlClient) Start(ctx context.Context) (status Status, err error) {
+	err = o.request(ctx, http.MethodPost, "/start", &status)
+	return
+}
+
+func (o *controlClient) Stop(ctx context.Context) (status Status, err error) {
+	err = o.request(ctx, http.MethodPost, "/stop", &status)
+	return
+}
+
+func (o *controlClient) Kill(ctx context.Context) (status Status, err error) {
+	err = o.request(ctx, http.MethodPost, "/kill", &status)
+	return
+}
+
+func (o *controlClient) RestartInstance(ctx context.Context, index int) (status Status, err error) {
+	err = o.request(ctx, http.MethodPost, "/restart?instance="+strconv.Itoa(index), &status)
+	return
+}
+
+func (o *controlClient) Tail(ctx context.Context, count int) (lines []OutputLine, err error) {
+	err = o.request(ctx, http.MethodGet, "/tail?lines="+strconv.Itoa(count), &lines)
+	return
+}
+
+func (o *controlClient) request(ctx context.Context, method, path string, v interface{}) error {
+	// a host is ignored by the dialer
+	req, err := http.NewRequest(method, "http://control"+path, nil)
+	if err != nil {
+		return errors.Wrapf(err, "failed to create a request %s", path)
+	}
+
+	resp, err := o.client.Do(req.WithContext(ctx))
+	if err != nil {
+		return errors.Wrapf(err, "failed to request %s", path)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		var controlErr controlError
+		json.NewDecoder(resp.Body).Decode(&controlErr)
+
+		return errors.Errorf("failed to request %s: %s %s", path, resp.Status, controlErr.Error)
+	}
+
+	return errors.Wrapf(json.NewDecoder(resp.Body).Decode(v), "failed to decode a response of %s", path)
+}
@@ -0,0 +1,208 @@
+package monitoring
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func controlRequest(t *testing.T, handler http.Handler, method, url string, expectedStatus int, v interface{}) {
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, httptest.NewRequest(method, url, nil))
+
+	if recorder.Code != expectedStatus {
+		t.Error("failed to request", method, url, ". Got", recorder.Code, recorder.Body.String(), ", but expected is", expectedStatus)
+		return
+	}
+
+	if v != nil {
+		if err := json.Unmarshal(recorder.Body.Bytes(), v); err != nil {
+			t.Error("failed to decode a response of", url, "with", err)
+		}
+	}
+}
+
+func TestControlHandler(t *testing.T) {
+	monitoring := NewMonitoring(&testParameter{
+		command:       "sh",
+		args:          []string{"-c", "echo started $" + EnvRun + "; sleep 10"},
+		runningMode:   RepeatInfinity,
+		parallelCount: 2,
+	})
+	handler := ControlHandler(monitoring)
+
+	var status Status
+
+	controlRequest(t, handler, http.MethodGet, "/status", http.StatusOK, &status)
+	if status.Stage != "stopped" || len(status.Instances) != 0 {
+		t.Error("failed to report a status before start. Got", status)
+	}
+
+	controlRequest(t, handler, http.MethodGet, "/start", http.StatusMethodNotAllowed, nil)
+
+	controlRequest(t, handler, http.MethodPost, "/start", http.StatusOK, &status)
+	if status.Stage != "monitoring" || len(status.Instances) != 2 || status.Instances[1].Pid == 0 {
+		t.Error("failed to start monitoring. Got", status)
+	}
+
+	controlRequest(t, handler, http.MethodPost, "/start", http.StatusConflict, nil)
+
+	pid := status.Instances[1].Pid
+	controlRequest(t, handler, http.MethodPost, "/restart?instance=1", http.StatusOK, nil)
+	controlRequest(t, handler, http.MethodPost, "/restart?instance=5", http.StatusConflict, nil)
+	controlRequest(t, handler, http.MethodPost, "/restart?instance=first", http.StatusBadRequest, nil)
+
+	var lines []OutputLine
+
+	// wait for an output of the restarted instance
+	for i := 0; i < 50; i++ {
+		if controlRequest(t, handler, http.MethodGet, "/tail?lines=1", http.StatusOK, &lines); len(lines) == 1 && lines[0].Line == "started 2\n" {
+			break
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+
+	controlRequest(t, handler, http.MethodGet, "/status", http.StatusOK, &status)
+	if instance := status.Instances[1]; instance.Runs != 2 || instance.Pid == pid || instance.Exited {
+		t.Error("failed to restart an instance. Got", instance)
+	}
+
+	if len(lines) != 1 || lines[0].Line != "started 2\n" || lines[0].Instance != 1 || lines[0].Stream != StreamStdOut {
+		t.Error("failed to tail an output. Got", lines)
+	}
+
+	controlRequest(t, handler, http.MethodGet, "/tail?lines=all", http.StatusBadRequest, nil)
+
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	if metrics := recorder.Body.String(); !strings.Contains(metrics, `monitoring_instance_restarts_total{instance="1"} 1`) {
+		t.Error("failed to serve metrics. Got", metrics)
+	}
+
+	controlRequest(t, handler, http.MethodPost, "/stop", http.StatusOK, &status)
+	if status.Stage != "finish" || status.Error != "" {
+		t.Error("failed to stop monitoring. Got", status)
+	}
+
+	for _, instance := range status.Instances {
+		if !instance.Exited {
+			t.Error("failed to report an exit of a stopped instance. Got", instance)
+		}
+	}
+
+	controlRequest(t, handler, http.MethodPost, "/start", http.StatusConflict, nil)
+}
+
+func TestListenControl(t *testing.T) {
+	dir, err := ioutil.TempDir("", "monitoring-control")
+	if err != nil {
+		t.Error("failed to create a temp dir with", err)
+		return
+	}
+	defer os.RemoveAll(dir)
+
+	if _, err := ListenControl("tcp", "0.0.0.0:0", nil); err == nil {
+		t.Error("failed to catch an error of a public address")
+	}
+
+	if _, err := ListenControl("udp", "127.0.0.1:0", nil); err == nil {
+		t.Error("failed to catch an error of an unsupported network")
+	}
+
+	notSocket := filepath.Join(dir, "file")
+	ioutil.WriteFile(notSocket, nil, 0644)
+	if _, err := ListenControl("unix", notSocket, nil); err == nil {
+		t.Error("failed to catch an error of a regular file")
+	}
+
+	monitoring := NewMonitoring(&testParameter{
+		command:     "sh",
+		args:        []string{"-c", "echo line 1; sleep 0.3; echo line 2; sleep 10"},
+		runningMode: RunOnce,
+	})
+	monitoring.Start(context.Background())
+	defer monitoring.Stop(context.Background())
+
+	socket := filepath.Join(dir, "control.sock")
+
+	server, err := ListenControl("unix", socket, monitoring)
+	if err != nil {
+		t.Error("failed to listen a socket with", err)
+		return
+	}
+
+	if _, err := ListenControl("unix", socket, monitoring); err == nil {
+		t.Error("failed to catch an error of a socket in use")
+	}
+
+	client := DialControl("unix", socket)
+
+	status, err := client.Status(context.Background())
+	if err != nil {
+		t.Error("failed to request a status with", err)
+	} else if status.Stage != "monitoring" || len(status.Instances) != 1 {
+		t.Error("failed to get a status. Got", status)
+	}
+
+	if _, err = client.RestartInstance(context.Background(), 3); err == nil {
+		t.Error("failed to catch an error of an unknown instance")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	req, _ := http.NewRequest(http.MethodGet, "http://control/tail?follow=1", nil)
+
+	resp, err := client.client.Do(req.WithContext(ctx))
+	if err != nil {
+		t.Error("failed to follow an output with", err)
+		cancel()
+		return
+	}
+
+	reader := bufio.NewReader(resp.Body)
+	readLine := func() string {
+		var line OutputLine
+
+		data, _ := reader.ReadBytes('\n')
+		json.Unmarshal(data, &line)
+
+		return line.Line
+	}
+
+	if exist := readLine(); exist != "line 1\n" {
+		t.Error("failed to get a tail. Got", exist)
+	}
+
+	if exist := readLine(); exist != "line 2\n" {
+		t.Error("failed to follow an output. Got", exist)
+	}
+
+	cancel()
+	resp.Body.Close()
+
+	if lines, err := client.Tail(context.Background(), 1); err != nil || len(lines) != 1 || lines[0].Line != "line 2\n" {
+		t.Error("failed to tail an output. Got", lines, err)
+	}
+
+	if status, err = client.Stop(context.Background()); err != nil || status.Stage != "finish" {
+		t.Error("failed to stop monitoring. Got", status, err)
+	}
+
+	closeCtx, closeCancel := context.WithTimeout(context.Background(), time.Second)
+	defer closeCancel()
+
+	if err := server.Close(closeCtx); err != nil {
+		t.Error("failed to close a server with", err)
+	}
+
+	if _, err := os.Stat(socket); !os.IsNotExist(err) {
+		t.Error("failed to remove a socket. Got", err)
+	}
+}
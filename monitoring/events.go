@@ -0,0 +1,95 @@
+//go:generate stringer -type=EventType
+
+package monitoring
+
+import (
+	"sync"
+	"time"
+)
+
+type EventType int
+
+const (
+	EventStarting EventType = iota
+	EventStarted
+	EventStartLineMatched
+	EventExited
+	EventRestarting
+	EventKilled
+	EventStopped
+	EventError
+	EventUnhealthy
+	EventResources
+	EventLimitExceeded
+	EventExpired
+)
+
+const (
+	// monitoringInstance is an instance index of events about the whole monitoring.
+	monitoringInstance = -1
+)
+
+// Event describes a step of a lifecycle of a monitored instance.
+// Instance is -1 for events about the whole monitoring.
+type Event struct {
+	Type     EventType
+	Instance int
+	Pid      int
+	Time     time.Time
+	Exit     *ExitRecord    // EventExited only
+	Err      error          // EventError, EventUnhealthy and EventLimitExceeded only
+	Stats    *ResourceStats // EventResources only
+}
+
+// eventBus delivers events to subscribers without blocking a publisher:
+// an event is dropped for a subscriber which buffer is full.
+type eventBus struct {
+	sync.RWMutex
+
+	subscribers map[int]chan Event
+	next        int
+}
+
+func (o *eventBus) subscribe(size int) (<-chan Event, func()) {
+	o.Lock()
+	defer o.Unlock()
+
+	if o.subscribers == nil {
+		o.subscribers = make(map[int]chan Event)
+	}
+
+	id, ch := o.next, make(chan Event, size)
+	o.subscribers[id] = ch
+	o.next++
+
+	var once sync.Once
+
+	return ch, func() {
+		once.Do(func() {
+			o.Lock()
+			delete(o.subscribers, id)
+			close(ch)
+			o.Unlock()
+		})
+	}
+}
+
+func (o *eventBus) emit(event Event) {
+	if o == nil {
+		return
+	}
+
+	if event.Time.IsZero() {
+		event.Time = time.Now()
+	}
+
+	o.RLock()
+	defer o.RUnlock()
+
+	for _, ch := range o.subscribers {
+		select {
+		case ch <- event:
+		default:
+		}
+	}
+}
@@ -0,0 +1,55 @@
+package monitoring
+
+import (
+	"testing"
+)
+
+func TestEventBus(t *testing.T) {
+	var bus eventBus
+
+	first, unsubscribeFirst := bus.subscribe(2)
+	second, unsubscribeSecond := bus.subscribe(1)
+	defer unsubscribeSecond()
+
+	bus.emit(Event{Type: EventStarting})
+	bus.emit(Event{Type: EventStarted})
+
+	if exist := (<-first).Type; exist != EventStarting {
+		t.Error("failed to deliver the first event. Got", exist)
+	}
+
+	if exist := (<-first).Type; exist != EventStarted {
+		t.Error("failed to deliver the second event. Got", exist)
+	}
+
+	if event := <-second; event.Type != EventStarting || event.Time.IsZero() {
+		t.Error("failed to deliver an event to the second subscriber. Got", event)
+	}
+
+	select {
+	case event := <-second:
+		t.Error("failed to drop an event on a full buffer. Got", event)
+	default:
+	}
+
+	unsubscribeFirst()
+	unsubscribeFirst()
+	bus.emit(Event{Type: EventStopped})
+
+	if _, ok := <-first; ok {
+		t.Error("failed to close a channel of an unsubscribed")
+	}
+
+	var nilBus *eventBus
+	nilBus.emit(Event{Type: EventStopped})
+}
+
+func TestEventType_String(t *testing.T) {
+	if exist, expected := EventStartLineMatched.String(), "EventStartLineMatched"; exist != expected {
+		t.Error("failed to convert to string. Got", exist, ", but expected is", expected)
+	}
+
+	if exist, expected := EventType(1024).String(), "EventType(1024)"; exist != expected {
+		t.Error("failed to convert to string an unknown type. Got", exist, ", but expected is", expected)
+	}
+}
@@ -0,0 +1,78 @@
+package monitoring
+
+import (
+	"os"
+	"sync"
+	"time"
+)
+
+const (
+	exitHistorySize = 16
+)
+
+// ExitRecord describes how a run of a command ended.
+type ExitRecord struct {
+	Instance  int
+	Run       int32
+	Pid       int
+	Code      int       // -1 if the command was terminated by a signal
+	Signal    os.Signal // nil if the command exited by itself
+	StartTime time.Time
+	EndTime   time.Time
+}
+
+func (o ExitRecord) Success() bool {
+	return o.Code == 0 && o.Signal == nil
+}
+
+func (o ExitRecord) Duration() time.Duration {
+	return o.EndTime.Sub(o.StartTime)
+}
+
+func newExitRecord(record ExitRecord, state *os.ProcessState) ExitRecord {
+	record.Code = -1
+	record.EndTime = time.Now()
+
+	if state != nil {
+		record.Pid = state.Pid()
+		record.Code, record.Signal = exitStatus(state)
+	}
+
+	return record
+}
+
+// exitHistory keeps last exitHistorySize records.
+type exitHistory struct {
+	sync.RWMutex
+
+	records []ExitRecord
+}
+
+func (o *exitHistory) add(record ExitRecord) {
+	o.Lock()
+	defer o.Unlock()
+
+	if len(o.records) >= exitHistorySize {
+		o.records = append(o.records[:0], o.records[1:]...)
+	}
+
+	o.records = append(o.records, record)
+}
+
+func (o *exitHistory) Last() (ExitRecord, bool) {
+	o.RLock()
+	defer o.RUnlock()
+
+	if len(o.records) == 0 {
+		return ExitRecord{}, false
+	}
+
+	return o.records[len(o.records)-1], true
+}
+
+func (o *exitHistory) Records() []ExitRecord {
+	o.RLock()
+	defer o.RUnlock()
+
+	return append([]ExitRecord{}, o.records...)
+}
@@ -0,0 +1,30 @@
+package monitoring
+
+import (
+	"testing"
+)
+
+func TestExitHistory(t *testing.T) {
+	var history exitHistory
+
+	if _, ok := history.Last(); ok {
+		t.Error("failed to check an empty history")
+	}
+
+	for i := 1; i <= exitHistorySize+5; i++ {
+		history.add(ExitRecord{Run: int32(i)})
+	}
+
+	records := history.Records()
+	if exist := len(records); exist != exitHistorySize {
+		t.Error("failed to bound a history. Got", exist, "records, but expected is", exitHistorySize)
+	}
+
+	if exist, expected := records[0].Run, int32(6); exist != expected {
+		t.Error("failed to keep the latest records. Got the first run", exist, ", but expected is", expected)
+	}
+
+	if last, ok := history.Last(); !ok || last.Run != exitHistorySize+5 {
+		t.Error("failed to get the last record. Got", last)
+	}
+}
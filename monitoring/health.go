@@ -0,0 +1,240 @@
+package monitoring
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"os"
+	"os/exec"
+	"strconv"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	defaultHealthInterval  = 10 * time.Second
+	defaultHealthTimeout   = time.Second
+	defaultHealthThreshold = 3
+
+	// EnvPid is added to the environment of ExecCheck with EnvInstance and EnvPort of a checked instance.
+	EnvPid = "MONITORING_PID"
+)
+
+// Instance describes a running instance of a command for checks.
+// Port is zero without PortParameter.
+type Instance struct {
+	Index int
+	Pid   int
+	Port  int
+}
+
+// A HealthCheck reports an error if an instance is unhealthy.
+type HealthCheck interface {
+	Check(ctx context.Context, instance Instance) error
+}
+
+// HealthCheckFunc is an adapter to use a function as a HealthCheck.
+type HealthCheckFunc func(ctx context.Context, instance Instance) error
+
+func (o HealthCheckFunc) Check(ctx context.Context, instance Instance) error {
+	return o(ctx, instance)
+}
+
+// HealthConfig describes a periodical liveness check of every instance.
+// An instance is restarted after FailureThreshold failed checks in a row.
+type HealthConfig struct {
+	Check            HealthCheck
+	Interval         time.Duration
+	Timeout          time.Duration
+	FailureThreshold int
+}
+
+// HealthParameter is an optional extension of MonitoringParameter.
+type HealthParameter interface {
+	HealthCheck() *HealthConfig
+}
+
+func (o HealthConfig) withDefaults() HealthConfig {
+	if o.Interval <= 0 {
+		o.Interval = defaultHealthInterval
+	}
+
+	if o.Timeout <= 0 {
+		o.Timeout = defaultHealthTimeout
+	}
+
+	if o.FailureThreshold <= 0 {
+		o.FailureThreshold = defaultHealthThreshold
+	}
+
+	return o
+}
+
+type httpCheck struct {
+	url    string
+	status int
+}
+
+// HTTPCheck expects the status of GET url; zero status means any 2xx.
+// PortPlaceholder of url is replaced by a port of an instance.
+func HTTPCheck(url string, status int) HealthCheck {
+	return &httpCheck{
+		url:    url,
+		status: status,
+	}
+}
+
+func (o *httpCheck) Check(ctx context.Context, instance Instance) error {
+	url := expandPort([]string{o.url}, instance.Port)[0]
+
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		return errors.Wrapf(err, "failed to create a request to %s", url)
+	}
+
+	resp, err := http.DefaultClient.Do(req.WithContext(ctx))
+	if err != nil {
+		return errors.Wrapf(err, "failed to request %s", url)
+	}
+	resp.Body.Close()
+
+	if (o.status == 0 && resp.StatusCode/100 == 2) || resp.StatusCode == o.status {
+		return nil
+	}
+
+	return errors.Errorf("unexpected status %d of %s", resp.StatusCode, url)
+}
+
+type tcpCheck struct {
+	address string
+}
+
+// TCPCheck expects the address accepts connections.
+// PortPlaceholder of the address is replaced by a port of an instance.
+func TCPCheck(address string) HealthCheck {
+	return &tcpCheck{
+		address: address,
+	}
+}
+
+func (o *tcpCheck) Check(ctx context.Context, instance Instance) error {
+	var dialer net.Dialer
+
+	address := expandPort([]string{o.address}, instance.Port)[0]
+
+	conn, err := dialer.DialContext(ctx, "tcp", address)
+	if err != nil {
+		return errors.Wrapf(err, "failed to connect to %s", address)
+	}
+
+	return conn.Close()
+}
+
+type execCheck struct {
+	command string
+	args    []string
+}
+
+// ExecCheck expects the command exits successfully.
+// PortPlaceholder of arguments is replaced by a port of an instance, the instance is in the environment too.
+func ExecCheck(command string, args ...string) HealthCheck {
+	return &execCheck{
+		command: command,
+		args:    args,
+	}
+}
+
+func (o *execCheck) Check(ctx context.Context, instance Instance) error {
+	args := expandPort(append([]string(nil), o.args...), instance.Port)
+
+	command := exec.CommandContext(ctx, o.command, args...)
+	command.Env = append(os.Environ(),
+		EnvInstance+"="+strconv.Itoa(instance.Index),
+		EnvPid+"="+strconv.Itoa(instance.Pid),
+	)
+
+	if instance.Port != 0 {
+		command.Env = append(command.Env, EnvPort+"="+strconv.Itoa(instance.Port))
+	}
+
+	output, err := command.CombinedOutput()
+
+	return errors.Wrapf(err, "failed to check with %s: %s", o.command, output)
+}
+
+// healthProcess checks an instance periodically and kills it when it is unhealthy,
+// so monitoringProcess restarts it the same as a crashed one.
+func (o *Monitoring) healthProcess(cmd *commandState, config HealthConfig, stop <-chan interface{}) {
+	ticker := time.NewTicker(config.Interval)
+	defer ticker.Stop()
+
+	var (
+		failures int
+		run      int32
+	)
+
+	for {
+		select {
+		case <-ticker.C:
+
+		case <-stop:
+			return
+		}
+
+		// skip a finished or stopped run, it is restarting
+		status := cmd.status()
+		if status.Runs == 0 || status.Exited || status.Stopped {
+			continue
+		}
+
+		if status.Runs != run {
+			run, failures = status.Runs, 0
+		}
+
+		err := checkHealth(status, config)
+		if err == nil {
+			failures = 0
+			continue
+		}
+
+		if failures++; failures < config.FailureThreshold {
+			continue
+		}
+
+		failures = 0
+		cmd.controlRun(run, func() {
+			cmd.markUnhealthy()
+			cmd.emit(EventUnhealthy, err)
+			cmd.Terminate(context.Background())
+		})
+	}
+}
+
+func checkHealth(status InstanceStatus, config HealthConfig) error {
+	ctx, cancel := context.WithTimeout(context.Background(), config.Timeout)
+	defer cancel()
+
+	return config.Check.Check(ctx, Instance{
+		Index: status.Index,
+		Pid:   status.Pid,
+		Port:  status.Port,
+	})
+}
+
+type fileCheck struct {
+	path string
+}
+
+// FileCheck expects the file exists.
+func FileCheck(path string) HealthCheck {
+	return &fileCheck{
+		path: path,
+	}
+}
+
+func (o *fileCheck) Check(context.Context, Instance) error {
+	_, err := os.Stat(o.path)
+
+	return errors.Wrapf(err, "failed to find %s", o.path)
+}
@@ -0,0 +1,121 @@
+package monitoring
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestHTTPCheck(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/health" {
+			w.WriteHeader(http.StatusNoContent)
+		} else {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer server.Close()
+
+	suites := []struct {
+		check   HealthCheck
+		healthy bool
+	}{
+		{HTTPCheck(server.URL+"/health", 0), true},
+		{HTTPCheck(server.URL+"/health", http.StatusNoContent), true},
+		{HTTPCheck(server.URL+"/health", http.StatusOK), false},
+		{HTTPCheck(server.URL+"/unknown", 0), false},
+		{HTTPCheck(server.URL+"/unknown", http.StatusServiceUnavailable), true},
+	}
+
+	for i, test := range suites {
+		if err := test.check.Check(context.Background(), Instance{}); (err == nil) != test.healthy {
+			t.Error("failed to check http #", i, ". Got", err, ", but expected healthy is", test.healthy)
+		}
+	}
+}
+
+func TestTCPCheck(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Error("failed to listen with", err)
+		return
+	}
+	address := ln.Addr().String()
+
+	if err := TCPCheck(address).Check(context.Background(), Instance{}); err != nil {
+		t.Error("failed to check a listened address with", err)
+	}
+
+	ln.Close()
+
+	if err := TCPCheck(address).Check(context.Background(), Instance{}); err == nil {
+		t.Error("failed to check a closed address")
+	}
+}
+
+func TestExecCheck(t *testing.T) {
+	if err := ExecCheck("true").Check(context.Background(), Instance{}); err != nil {
+		t.Error("failed to check a successful command with", err)
+	}
+
+	if err := ExecCheck("sh", "-c", "exit 1").Check(context.Background(), Instance{}); err == nil {
+		t.Error("failed to check a failed command")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	if err := ExecCheck("sleep", "5").Check(ctx, Instance{}); err == nil {
+		t.Error("failed to check a timeout of a command")
+	}
+}
+
+func TestExecCheck_Instance(t *testing.T) {
+	check := ExecCheck("sh", "-c", `test "$1:$`+EnvInstance+`:$`+EnvPid+`:$`+EnvPort+`" = "8081:2:1234:8081"`, "check", PortPlaceholder)
+
+	if err := check.Check(context.Background(), Instance{Index: 2, Pid: 1234, Port: 8081}); err != nil {
+		t.Error("failed to pass an instance to a command with", err)
+	}
+
+	if err := check.Check(context.Background(), Instance{Index: 1, Pid: 1234, Port: 8082}); err == nil {
+		t.Error("failed to check another instance with a command")
+	}
+}
+
+func TestCommandState_ControlRun(t *testing.T) {
+	monitoring := NewMonitoring(&testParameter{
+		command:       "sh",
+		args:          []string{"-c", "echo started; sleep 10"},
+		runningMode:   RepeatInfinity,
+		parallelCount: 1,
+	})
+
+	monitoring.Start(context.Background())
+	defer monitoring.Stop(context.Background())
+
+	cmd := monitoring.commands()[0]
+
+	acted := false
+	cmd.controlRun(2, func() { acted = true })
+	if acted {
+		t.Error("failed to skip an action on a replaced run")
+	}
+
+	cmd.controlRun(1, func() { acted = true })
+	if !acted {
+		t.Error("failed to act on the current run")
+	}
+
+	if err := monitoring.StopInstance(context.Background(), 0); err != nil {
+		t.Error("failed to stop an instance with", err)
+	}
+
+	acted = false
+	cmd.controlRun(1, func() { acted = true })
+	if acted {
+		t.Error("failed to skip an action on a stopped instance")
+	}
+}
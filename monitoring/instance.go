@@ -0,0 +1,170 @@
+package monitoring
+
+import (
+	"context"
+	"sync/atomic"
+
+	"github.com/pkg/errors"
+)
+
+// StartInstance starts an instance stopped by StopInstance or finished without a restart
+// and waits it is ready. A running instance is kept as is.
+func (o *Monitoring) StartInstance(ctx context.Context, index int) error {
+	return o.waitCommand(context.WithValue(ctx, "instance", index), cmdStartInstance)
+}
+
+// StopInstance stops an instance gracefully if a stop signal is set, or kills it otherwise.
+// Monitoring doesn't restart it till StartInstance.
+func (o *Monitoring) StopInstance(ctx context.Context, index int) error {
+	return o.waitCommand(context.WithValue(ctx, "instance", index), cmdStopInstance)
+}
+
+// RestartInstance stops an instance and starts it again regardless of RestartRuleParameter.
+func (o *Monitoring) RestartInstance(ctx context.Context, index int) error {
+	return o.waitCommand(context.WithValue(ctx, "instance", index), cmdRestartInstance)
+}
+
+// RollingRestart restarts instances one by one waiting every one is ready before the next,
+// so others keep serving. It breaks on the first failed instance.
+func (o *Monitoring) RollingRestart(ctx context.Context) error {
+	return o.waitCommand(ctx, cmdRollingRestart)
+}
+
+func (o *Monitoring) waitCommand(ctx context.Context, command monitoringCommand) error {
+	wait := o.runCommand(ctx, command)
+	<-wait.Done()
+
+	return wait.(*commandCtx).HasError()
+}
+
+func (o *Monitoring) commandInstance(ctx context.Context, command monitoringCommand) error {
+	if !o.isRunning() {
+		return errors.Errorf("failed to execute %v - monitoring isn't active", command)
+	}
+
+	if command == cmdRollingRestart {
+		return o.rollingRestart(ctx)
+	}
+
+	index, _ := ctx.Value("instance").(int)
+
+	cmds := o.commands()
+	if index < 0 || index >= len(cmds) {
+		return errors.Errorf("failed to execute %v - unknown instance #%d", command, index)
+	}
+
+	switch command {
+	case cmdStartInstance:
+		return o.startInstance(ctx, cmds[index])
+
+	case cmdStopInstance:
+		return o.stopInstance(ctx, cmds[index])
+
+	default:
+		return o.restartInstance(ctx, cmds[index])
+	}
+}
+
+func (o *Monitoring) monitorInstance(cmd *commandState) {
+	monitored, leave := make(chan struct{}), make(chan struct{})
+	cmd.monitored, cmd.leave = monitored, leave
+
+	go func() {
+		defer close(monitored)
+
+		o.monitoringProcess(cmd, leave)
+	}()
+}
+
+func (o *Monitoring) stopInstance(ctx context.Context, cmd *commandState) (err error) {
+	// a pending restart holds the control lock till its start check, leaving cancels the check
+	cmd.leaveMonitoring()
+
+	cmd.control.Lock()
+	if cmd.isHeld() {
+		cmd.control.Unlock()
+		return nil
+	}
+	cmd.hold()
+
+	select {
+	case <-cmd.Wait():
+	default:
+		err = cmd.Terminate(ctx)
+		cmd.emit(EventKilled, nil)
+	}
+	cmd.control.Unlock()
+
+	if err != nil {
+		return errors.Wrapf(err, "failed to stop instance #%d", cmd.index)
+	}
+
+	return o.waitMonitored(ctx, cmd)
+}
+
+// waitMonitored waits monitoring of a held instance notices the exit and leaves it.
+func (o *Monitoring) waitMonitored(ctx context.Context, cmd *commandState) error {
+	select {
+	case <-cmd.monitored:
+		return nil
+
+	case <-ctx.Done():
+		return errors.Errorf("failed to wait for instance #%d - user cancel", cmd.index)
+	}
+}
+
+func (o *Monitoring) startInstance(ctx context.Context, cmd *commandState) error {
+	if !cmd.isHeld() {
+		select {
+		case <-cmd.monitored:
+		default:
+			return nil
+		}
+	} else if err := o.waitMonitored(ctx, cmd); err != nil {
+		return err
+	}
+
+	// an instance finished without a restart is counted to stop monitoring
+	if atomic.CompareAndSwapInt32(&cmd.finished, 1, 0) {
+		atomic.AddInt32(&o.finished, -1)
+	}
+
+	cmd.control.Lock()
+	defer cmd.control.Unlock()
+
+	cmd.release()
+
+	wait := make(chan error, 1)
+	cmd.Init(o.MonitoringParameter)
+	cmd.Run(ctx, wait)
+
+	if err := <-wait; err != nil {
+		// a process could be started, but failed a start line check
+		cmd.hold()
+		cmd.Kill()
+
+		return errors.Wrapf(err, "failed to start instance #%d", cmd.index)
+	}
+
+	o.monitorInstance(cmd)
+
+	return nil
+}
+
+func (o *Monitoring) restartInstance(ctx context.Context, cmd *commandState) error {
+	if err := o.stopInstance(ctx, cmd); err != nil {
+		return err
+	}
+
+	return o.startInstance(ctx, cmd)
+}
+
+func (o *Monitoring) rollingRestart(ctx context.Context) error {
+	for _, cmd := range o.commands() {
+		if err := o.restartInstance(ctx, cmd); err != nil {
+			return errors.Wrap(err, "failed to restart instances")
+		}
+	}
+
+	return nil
+}
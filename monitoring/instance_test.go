@@ -0,0 +1,248 @@
+package monitoring
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestMonitoring_StopStartInstance(t *testing.T) {
+	monitoring := NewMonitoring(&testParameter{
+		command:       "sh",
+		args:          []string{"-c", "echo started; sleep 10"},
+		runningMode:   RepeatInfinity,
+		parallelCount: 2,
+	})
+
+	if err := monitoring.StopInstance(context.Background(), 0); err == nil {
+		t.Error("failed to catch an error of inactive monitoring")
+	}
+
+	monitoring.Start(context.Background())
+	defer monitoring.Stop(context.Background())
+
+	if err := monitoring.StopInstance(context.Background(), 2); err == nil {
+		t.Error("failed to catch an error of an unknown instance")
+	}
+
+	if err := monitoring.StopInstance(context.Background(), 0); err != nil {
+		t.Error("failed to stop an instance with", err)
+	}
+
+	status := monitoring.Status()
+	if instance := status.Instances[0]; !instance.Stopped || !instance.Exited || instance.Runs != 1 {
+		t.Error("failed to stop an instance. Got", instance)
+	}
+
+	if instance := status.Instances[1]; instance.Stopped || instance.Exited {
+		t.Error("failed to keep other instances. Got", instance)
+	}
+
+	if err := monitoring.StopInstance(context.Background(), 0); err != nil {
+		t.Error("failed to stop a stopped instance with", err)
+	}
+
+	if err := monitoring.StartInstance(context.Background(), 0); err != nil {
+		t.Error("failed to start an instance with", err)
+	}
+
+	if instance := monitoring.Status().Instances[0]; instance.Stopped || instance.Exited || instance.Runs != 2 {
+		t.Error("failed to start an instance. Got", instance)
+	}
+
+	if err := monitoring.StartInstance(context.Background(), 1); err != nil {
+		t.Error("failed to keep a running instance with", err)
+	}
+
+	if runs := monitoring.Status().Instances[1].Runs; runs != 1 {
+		t.Error("failed to keep a running instance. Got runs", runs, ", but expected is", 1)
+	}
+
+	if err := monitoring.HasError(); err != nil {
+		t.Error("failed to run monitoring with", err)
+	}
+}
+
+func TestMonitoring_RestartInstanceFailure(t *testing.T) {
+	monitoring := NewMonitoring(&testParameter{
+		command:       "sh",
+		args:          []string{"-c", "if [ $" + EnvRun + " -gt 1 ]; then echo failed >&2; sleep 10; fi; echo started; sleep 10"},
+		runningMode:   RepeatInfinity,
+		parallelCount: 1,
+	})
+
+	monitoring.Start(context.Background())
+	defer monitoring.Stop(context.Background())
+
+	if err := monitoring.RestartInstance(context.Background(), 0); err == nil {
+		t.Error("failed to catch an error of a start")
+	}
+
+	if instance := monitoring.Status().Instances[0]; !instance.Stopped {
+		t.Error("failed to keep a failed instance stopped. Got", instance)
+	}
+
+	if err := monitoring.HasError(); err != nil {
+		t.Error("failed to keep an error of an instance from monitoring. Got", err)
+	}
+}
+
+func TestMonitoring_RollingRestart(t *testing.T) {
+	monitoring := NewMonitoring(&testParameter{
+		command:       "sh",
+		args:          []string{"-c", "echo started; sleep 10"},
+		runningMode:   RepeatInfinity,
+		parallelCount: 3,
+	})
+
+	if err := monitoring.RollingRestart(context.Background()); err == nil {
+		t.Error("failed to catch an error of inactive monitoring")
+	}
+
+	monitoring.Start(context.Background())
+
+	events, unsubscribe := monitoring.Subscribe(100)
+
+	if err := monitoring.RollingRestart(context.Background()); err != nil {
+		t.Error("failed to restart instances with", err)
+	}
+	unsubscribe()
+
+	// an instance is started before the next one is stopped
+	var order []int
+	for event := range events {
+		switch event.Type {
+		case EventKilled, EventStartLineMatched:
+			order = append(order, event.Instance)
+		}
+	}
+
+	if expected := []int{0, 0, 1, 1, 2, 2}; len(order) != len(expected) || order[1] != 0 || order[2] != 1 || order[4] != 2 {
+		t.Error("failed to restart instances one by one. Got", order, ", but expected is", expected)
+	}
+
+	for _, instance := range monitoring.Status().Instances {
+		if instance.Runs != 2 || instance.Exited {
+			t.Error("failed to restart an instance. Got", instance)
+		}
+	}
+
+	monitoring.Stop(context.Background())
+	monitoring.Wait()
+
+	if err := monitoring.HasError(); err != nil {
+		t.Error("failed to run monitoring with", err)
+	}
+
+	if err := monitoring.RestartInstance(context.Background(), 0); err == nil {
+		t.Error("failed to catch an error of stopped monitoring")
+	}
+}
+
+func TestMonitoring_StopInstanceRestarting(t *testing.T) {
+	monitoring := NewMonitoring(&testParameter{
+		command:       "sh",
+		args:          []string{"-c", "if [ $" + EnvRun + " -gt 1 ]; then sleep 0.3; echo failed >&2; sleep 10; fi; echo started; sleep 0.2; exit 1"},
+		runningMode:   RepeatInfinity,
+		parallelCount: 1,
+	})
+
+	monitoring.Start(context.Background())
+	defer monitoring.Stop(context.Background())
+
+	// the second run would fail its start check, stopping the instance cancels the check
+	time.Sleep(350 * time.Millisecond)
+
+	stopped := make(chan error, 1)
+	go func() {
+		stopped <- monitoring.StopInstance(context.Background(), 0)
+	}()
+
+	select {
+	case err := <-stopped:
+		if err != nil {
+			t.Error("failed to stop an instance while it is restarting with", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("failed to stop an instance while it is restarting")
+	}
+
+	if err := monitoring.HasError(); err != nil {
+		t.Error("failed to cancel a restart of a stopped instance. Got", err)
+	}
+
+	if instance := monitoring.Status().Instances[0]; !instance.Stopped || !instance.Exited {
+		t.Error("failed to stop an instance while it is restarting. Got", instance)
+	}
+}
+
+func TestMonitoring_StopInstanceBackoff(t *testing.T) {
+	monitoring := NewMonitoring(&testParameter{
+		command:       "sh",
+		args:          []string{"-c", "echo started; sleep 0.1; exit 1"},
+		runningMode:   RepeatInfinity,
+		parallelCount: 1,
+		restartPolicy: ConstantRestart(10 * time.Second),
+	})
+
+	monitoring.Start(context.Background())
+
+	// the instance waits for a restart
+	time.Sleep(300 * time.Millisecond)
+
+	start := time.Now()
+
+	if err := monitoring.StopInstance(context.Background(), 0); err != nil {
+		t.Error("failed to stop an instance with", err)
+	}
+
+	monitoring.Stop(context.Background())
+
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Error("failed to interrupt a restart delay. Got", elapsed)
+	}
+
+	if instance := monitoring.Status().Instances[0]; !instance.Stopped || instance.Runs != 1 {
+		t.Error("failed to stop an instance. Got", instance)
+	}
+}
+
+func TestMonitoring_StopInstanceHungRestart(t *testing.T) {
+	monitoring := NewMonitoring(&testParameter{
+		command:       "sh",
+		args:          []string{"-c", "if [ $" + EnvRun + " -gt 1 ]; then exec sleep 30; fi; echo started; sleep 0.1; exit 1"},
+		runningMode:   RepeatInfinity,
+		parallelCount: 1,
+	})
+
+	monitoring.Start(context.Background())
+
+	// the restarted run never passes its start check
+	time.Sleep(300 * time.Millisecond)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	if err := monitoring.StopInstance(ctx, 0); err != nil {
+		t.Error("failed to stop an instance with a hung restart with", err)
+	}
+
+	stopped := make(chan interface{})
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+
+		monitoring.Stop(ctx)
+		close(stopped)
+	}()
+
+	select {
+	case <-stopped:
+	case <-time.After(3 * time.Second):
+		t.Fatal("failed to stop monitoring after an instance with a hung restart was stopped")
+	}
+
+	if instance := monitoring.Status().Instances[0]; !instance.Stopped || !instance.Exited {
+		t.Error("failed to stop an instance with a hung restart. Got", instance)
+	}
+}
@@ -0,0 +1,102 @@
+package monitoring
+
+import (
+	"context"
+	"math"
+	"math/rand"
+	"time"
+)
+
+const (
+	lifetimeCheckInterval = time.Second
+)
+
+// LifetimeConfig recycles leaking instances. A run is restarted gracefully after MaxLifetime randomized
+// by +/- Jitter part of it, so parallel instances don't restart together; the part is in [0, 1].
+// An instance is recycled after every MaxRuns runs too: the exit of the last run restarts it regardless
+// of RestartRuleParameter and with a reset delay of RestartPolicy.
+// Zero fields are disabled. Both keep instances running apart from RunningMode, which stops the whole monitoring.
+type LifetimeConfig struct {
+	MaxLifetime time.Duration
+	Jitter      float64
+	MaxRuns     int32
+}
+
+// LifetimeParameter is an optional extension of MonitoringParameter.
+type LifetimeParameter interface {
+	Lifetime() *LifetimeConfig
+}
+
+// lifetime returns a randomized lifetime of a run.
+func (o LifetimeConfig) lifetime() time.Duration {
+	jitter := math.Max(0, math.Min(1, o.Jitter))
+	lifetime := float64(o.MaxLifetime)
+
+	return time.Duration(lifetime + lifetime*jitter*(2*rand.Float64()-1))
+}
+
+func (o *Monitoring) lifetimeConfig() *LifetimeConfig {
+	if parameter, ok := o.MonitoringParameter.(LifetimeParameter); ok {
+		return parameter.Lifetime()
+	}
+
+	return nil
+}
+
+// runsExhausted reports an instance has completed another MaxRuns runs.
+func (o *Monitoring) runsExhausted(cmd *commandState) bool {
+	config := o.lifetimeConfig()
+
+	return config != nil && config.MaxRuns > 0 && cmd.RunCount()%config.MaxRuns == 0
+}
+
+// lifetimeProcess stops a run over its lifetime, so monitoringProcess restarts it regardless of RestartRuleParameter.
+func (o *Monitoring) lifetimeProcess(cmd *commandState, config LifetimeConfig, stop <-chan interface{}) {
+	interval := config.MaxLifetime / 10
+	if interval <= 0 || interval > lifetimeCheckInterval {
+		interval = lifetimeCheckInterval
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	var (
+		run      int32
+		deadline time.Time
+	)
+
+	for {
+		select {
+		case <-ticker.C:
+
+		case <-stop:
+			return
+		}
+
+		// skip a finished or stopped run, it is restarting
+		status := cmd.status()
+		if status.Runs == 0 || status.Exited || status.Stopped {
+			continue
+		}
+
+		if status.Runs != run {
+			run, deadline = status.Runs, status.StartTime.Add(config.lifetime())
+		}
+
+		if time.Now().Before(deadline) {
+			continue
+		}
+
+		o.recycleInstance(cmd, run)
+	}
+}
+
+// recycleInstance stops the run under the control lock, so neither monitoring nor commands of the instance
+// restart it meanwhile.
+func (o *Monitoring) recycleInstance(cmd *commandState, run int32) {
+	cmd.controlRun(run, func() {
+		cmd.markRecycled()
+		cmd.emit(EventExpired, nil)
+		cmd.Terminate(context.Background())
+	})
+}
@@ -0,0 +1,108 @@
+package monitoring
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestLifetimeConfig(t *testing.T) {
+	config := LifetimeConfig{MaxLifetime: time.Second, Jitter: 0.1}
+
+	for i := 0; i < 100; i++ {
+		if lifetime := config.lifetime(); lifetime < 900*time.Millisecond || lifetime > 1100*time.Millisecond {
+			t.Error("failed to randomize a lifetime. Got", lifetime, ", but expected is in", 900*time.Millisecond, 1100*time.Millisecond)
+		}
+	}
+
+	if lifetime := (LifetimeConfig{MaxLifetime: time.Second}).lifetime(); lifetime != time.Second {
+		t.Error("failed to get a lifetime without a jitter. Got", lifetime, ", but expected is", time.Second)
+	}
+}
+
+func TestMonitoring_Lifetime(t *testing.T) {
+	monitoring := NewMonitoring(&testParameter{
+		command:       "sh",
+		args:          []string{"-c", "echo started; sleep 10"},
+		runningMode:   RepeatInfinity,
+		parallelCount: 1,
+		lifetime:      &LifetimeConfig{MaxLifetime: 100 * time.Millisecond},
+	})
+
+	events, unsubscribe := monitoring.Subscribe(100)
+	defer unsubscribe()
+
+	monitoring.Start(context.Background())
+	defer monitoring.Stop(context.Background())
+
+	expired := 0
+
+	// the instance is terminated after its lifetime and started again
+	for matched := 0; matched < 2; {
+		select {
+		case event := <-events:
+			switch event.Type {
+			case EventExpired:
+				expired++
+			case EventStartLineMatched:
+				matched++
+			}
+
+		case <-time.After(5 * time.Second):
+			t.Fatal("failed to wait for a recycle")
+		}
+	}
+
+	if expired == 0 {
+		t.Error("failed to report an expired instance")
+	}
+
+	if err := monitoring.HasError(); err != nil {
+		t.Error("failed to recycle an instance with", err)
+	}
+}
+
+func TestMonitoring_MaxRuns(t *testing.T) {
+	monitoring := NewMonitoring(&testParameter{
+		command:       "sh",
+		args:          []string{"-c", "echo started; sleep 0.1; [ $" + EnvRun + " -gt 1 ]"},
+		runningMode:   RepeatInfinity,
+		parallelCount: 1,
+		restartMode:   RestartOnFailure,
+		lifetime:      &LifetimeConfig{MaxRuns: 2},
+	})
+
+	events, unsubscribe := monitoring.Subscribe(100)
+	defer unsubscribe()
+
+	monitoring.Start(context.Background())
+	defer monitoring.Stop(context.Background())
+
+	// only the first run fails, a successful run isn't restarted except the last one of MaxRuns runs
+	done := make(chan interface{})
+	go func() {
+		monitoring.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("failed to stop monitoring after runs of instances")
+	}
+
+	if runs := monitoring.Status().Instances[0].Runs; runs != 3 {
+		t.Error("failed to recycle an instance after runs. Got", runs, ", but expected is", 3)
+	}
+
+	expired := 0
+	for len(events) > 0 {
+		if event := <-events; event.Type == EventExpired {
+			expired++
+		}
+	}
+
+	if expired != 1 {
+		t.Error("failed to report recycling of an instance. Got", expired, ", but expected is", 1)
+	}
+}
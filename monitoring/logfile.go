@@ -0,0 +1,334 @@
+package monitoring
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/7phs/tools/common"
+)
+
+// LogPrefix is a set of fields written before every line of a log file.
+type LogPrefix int
+
+const (
+	PrefixTimestamp LogPrefix = 1 << iota
+	PrefixInstance
+	PrefixStream
+)
+
+const (
+	defaultLogTimeFormat = "2006-01-02T15:04:05.000Z07:00"
+	backupTimeFormat     = "20060102T150405.000000000"
+	compressExt          = ".gz"
+)
+
+// LogFileConfig describes files to write an output of instances to. Every instance writes
+// both streams to Dir/Name.<instance>.log. A file is rotated when it grows over MaxSize
+// or becomes older than MaxAge (checked on every line); zero disables a rule.
+type LogFileConfig struct {
+	Dir        string
+	Name       string
+	MaxSize    int64
+	MaxAge     time.Duration
+	MaxBackups int // zero keeps all rotated files
+	Compress   bool
+	Prefix     LogPrefix
+	TimeFormat string
+}
+
+// LogFileParameter is an optional extension of MonitoringParameter to write
+// an output of instances to rotated log files.
+type LogFileParameter interface {
+	LogFile() *LogFileConfig
+}
+
+type logFiles struct {
+	sync.Mutex
+
+	config LogFileConfig
+	files  map[int]*rotatingFile
+	closed bool
+}
+
+func LogFiles(config LogFileConfig) *logFiles {
+	if config.TimeFormat == "" {
+		config.TimeFormat = defaultLogTimeFormat
+	}
+
+	return &logFiles{
+		config: config,
+		files:  make(map[int]*rotatingFile),
+	}
+}
+
+// Sink returns a sink of the stream of every instance, the name is used for a prefix.
+// Lines are dropped after Close, outputs of instances could be drained still.
+func (o *logFiles) Sink(stream string) OutputSink {
+	return OutputSinkFunc(func(instance int, line string) {
+		if file := o.file(instance); file != nil {
+			file.write(o.prefix(instance, stream) + line)
+		}
+	})
+}
+
+func (o *logFiles) Path(instance int) string {
+	return filepath.Join(o.config.Dir, fmt.Sprintf("%s.%d.log", o.config.Name, instance))
+}
+
+func (o *logFiles) Close() error {
+	o.Lock()
+	defer o.Unlock()
+
+	var errs []error
+
+	for instance, file := range o.files {
+		errs = append(errs, errors.Wrapf(file.close(), "instance #%d", instance))
+	}
+	o.files = make(map[int]*rotatingFile)
+	o.closed = true
+
+	return common.SeveralErrors("failed to close log files", errs...)
+}
+
+// file returns nil after Close.
+func (o *logFiles) file(instance int) *rotatingFile {
+	o.Lock()
+	defer o.Unlock()
+
+	if o.closed {
+		return nil
+	}
+
+	file, ok := o.files[instance]
+	if !ok {
+		file = &rotatingFile{
+			path:   o.Path(instance),
+			config: o.config,
+		}
+		o.files[instance] = file
+	}
+
+	return file
+}
+
+func (o *logFiles) prefix(instance int, stream string) (prefix string) {
+	if o.config.Prefix&PrefixTimestamp != 0 {
+		prefix += time.Now().Format(o.config.TimeFormat) + " "
+	}
+
+	if o.config.Prefix&PrefixInstance != 0 {
+		prefix += fmt.Sprintf("[#%d] ", instance)
+	}
+
+	if o.config.Prefix&PrefixStream != 0 {
+		prefix += "[" + stream + "] "
+	}
+
+	return
+}
+
+type rotatingFile struct {
+	sync.Mutex
+
+	path   string
+	config LogFileConfig
+
+	file     *os.File
+	size     int64
+	openTime time.Time
+	err      error
+	closed   bool
+
+	backups sync.WaitGroup
+}
+
+func (o *rotatingFile) write(line string) {
+	o.Lock()
+	defer o.Unlock()
+
+	// a line is late for a closed file
+	if o.closed {
+		return
+	}
+
+	if o.needRotate(int64(len(line))) {
+		o.catchError(o.rotate())
+	}
+
+	if o.file == nil && !o.catchError(o.open()) {
+		return
+	}
+
+	n, err := io.WriteString(o.file, line)
+	o.size += int64(n)
+	o.catchError(errors.Wrapf(err, "failed to write to %s", o.path))
+}
+
+func (o *rotatingFile) needRotate(size int64) bool {
+	if o.file == nil {
+		return false
+	}
+
+	return (o.config.MaxSize > 0 && o.size > 0 && o.size+size > o.config.MaxSize) ||
+		(o.config.MaxAge > 0 && time.Since(o.openTime) >= o.config.MaxAge)
+}
+
+func (o *rotatingFile) open() error {
+	var file *os.File
+
+	err := keepUmask(func() (err error) {
+		if err = os.MkdirAll(filepath.Dir(o.path), 0755); err != nil {
+			return errors.Wrapf(err, "failed to create a directory of %s", o.path)
+		}
+
+		file, err = os.OpenFile(o.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+
+		return errors.Wrapf(err, "failed to open %s", o.path)
+	})
+	if err != nil {
+		return err
+	}
+
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return errors.Wrapf(err, "failed to get info of %s", o.path)
+	}
+
+	o.file, o.size, o.openTime = file, info.Size(), time.Now()
+
+	return nil
+}
+
+func (o *rotatingFile) rotate() error {
+	if err := o.file.Close(); err != nil {
+		return errors.Wrapf(err, "failed to close %s", o.path)
+	}
+	o.file = nil
+
+	backup := o.path + "." + time.Now().Format(backupTimeFormat)
+	if err := os.Rename(o.path, backup); err != nil {
+		return errors.Wrapf(err, "failed to rotate %s", o.path)
+	}
+
+	// compress and clean up in background to don't stall an output
+	o.backups.Add(1)
+	go func() {
+		defer o.backups.Done()
+
+		if o.config.Compress {
+			o.catchLockedError(compressFile(backup))
+		}
+
+		o.catchLockedError(o.removeBackups())
+	}()
+
+	return nil
+}
+
+func (o *rotatingFile) removeBackups() error {
+	if o.config.MaxBackups <= 0 {
+		return nil
+	}
+
+	pattern := o.path + ".*"
+	if o.config.Compress {
+		pattern += compressExt
+	}
+
+	backups, err := filepath.Glob(pattern)
+	if err != nil {
+		return errors.Wrapf(err, "failed to list backups of %s", o.path)
+	}
+
+	// timestamps of names are sorted as strings
+	sort.Strings(backups)
+
+	var errs []error
+	for i := 0; i < len(backups)-o.config.MaxBackups; i++ {
+		// could be removed by a previous rotation concurrently
+		if err := os.Remove(backups[i]); err != nil && !os.IsNotExist(err) {
+			errs = append(errs, err)
+		}
+	}
+
+	return common.SeveralErrors("failed to remove backups of "+o.path, errs...)
+}
+
+func (o *rotatingFile) close() error {
+	o.Lock()
+	o.closed = true
+	if o.file != nil {
+		o.catchError(o.file.Close())
+		o.file = nil
+	}
+	o.Unlock()
+
+	o.backups.Wait()
+
+	o.Lock()
+	defer o.Unlock()
+
+	return o.err
+}
+
+// catchError keeps the first error and reports there was no error.
+func (o *rotatingFile) catchError(err error) bool {
+	if err != nil && o.err == nil {
+		o.err = err
+	}
+
+	return err == nil
+}
+
+func (o *rotatingFile) catchLockedError(err error) {
+	o.Lock()
+	o.catchError(err)
+	o.Unlock()
+}
+
+func compressFile(path string) error {
+	src, err := os.Open(path)
+	if err != nil {
+		return errors.Wrapf(err, "failed to open %s to compress", path)
+	}
+
+	tmp := path + compressExt + ".tmp"
+
+	var dst *os.File
+
+	err = keepUmask(func() (err error) {
+		dst, err = os.OpenFile(tmp, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644)
+		return err
+	})
+	if err != nil {
+		src.Close()
+		return errors.Wrapf(err, "failed to create %s", tmp)
+	}
+
+	writer := gzip.NewWriter(dst)
+
+	_, errCopy := io.Copy(writer, src)
+	errGzip := writer.Close()
+	errDst := dst.Close()
+	src.Close()
+
+	if err = common.SeveralErrors("failed to compress "+path, errCopy, errGzip, errDst); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+
+	if err = os.Rename(tmp, path+compressExt); err != nil {
+		return errors.Wrapf(err, "failed to rename %s", tmp)
+	}
+
+	return errors.Wrapf(os.Remove(path), "failed to remove compressed %s", path)
+}
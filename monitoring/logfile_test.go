@@ -0,0 +1,175 @@
+package monitoring
+
+import (
+	"compress/gzip"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLogFiles_Prefix(t *testing.T) {
+	dir, err := ioutil.TempDir("", "logfiles")
+	if err != nil {
+		t.Error("failed to create a temp dir with", err)
+		return
+	}
+	defer os.RemoveAll(dir)
+
+	files := LogFiles(LogFileConfig{
+		Dir:    dir,
+		Name:   "test",
+		Prefix: PrefixTimestamp | PrefixInstance | PrefixStream,
+	})
+
+	files.Sink("stdout").WriteLine(1, "hello\n")
+	files.Sink("stderr").WriteLine(1, "world\n")
+
+	if err := files.Close(); err != nil {
+		t.Error("failed to close log files with", err)
+	}
+
+	content, err := ioutil.ReadFile(filepath.Join(dir, "test.1.log"))
+	if err != nil {
+		t.Error("failed to read a log file with", err)
+		return
+	}
+
+	expected := regexp.MustCompile(`^\d{4}-\d{2}-\d{2}T\S+ \[#1\] \[stdout\] hello\n\d{4}-\d{2}-\d{2}T\S+ \[#1\] \[stderr\] world\n$`)
+	if !expected.Match(content) {
+		t.Error("unexpected content of a log file. Got '", string(content), "'")
+	}
+}
+
+func TestLogFiles_Closed(t *testing.T) {
+	dir, err := ioutil.TempDir("", "logfiles")
+	if err != nil {
+		t.Error("failed to create a temp dir with", err)
+		return
+	}
+	defer os.RemoveAll(dir)
+
+	files := LogFiles(LogFileConfig{Dir: dir, Name: "test"})
+	sink := files.Sink("stdout")
+
+	sink.WriteLine(0, "hello\n")
+	file := files.file(0)
+
+	if err := files.Close(); err != nil {
+		t.Error("failed to close log files with", err)
+	}
+
+	// late lines of drained outputs are dropped instead of reopening files
+	sink.WriteLine(0, "late\n")
+	sink.WriteLine(1, "late\n")
+	file.write("late\n")
+
+	if content, err := ioutil.ReadFile(filepath.Join(dir, "test.0.log")); err != nil || string(content) != "hello\n" {
+		t.Error("failed to drop lines after close. Got '", string(content), "'", err)
+	}
+
+	if _, err := os.Stat(filepath.Join(dir, "test.1.log")); !os.IsNotExist(err) {
+		t.Error("failed to skip a file after close. Got", err)
+	}
+
+	if len(files.files) != 0 || file.file != nil {
+		t.Error("failed to keep files closed. Got", files.files, file.file)
+	}
+}
+
+func TestLogFiles_RotateSize(t *testing.T) {
+	dir, err := ioutil.TempDir("", "logfiles")
+	if err != nil {
+		t.Error("failed to create a temp dir with", err)
+		return
+	}
+	defer os.RemoveAll(dir)
+
+	files := LogFiles(LogFileConfig{
+		Dir:        dir,
+		Name:       "test",
+		MaxSize:    20,
+		MaxBackups: 2,
+		Compress:   true,
+	})
+
+	sink := files.Sink("stdout")
+	for i := 0; i < 10; i++ {
+		sink.WriteLine(0, "0123456789\n")
+	}
+
+	if err := files.Close(); err != nil {
+		t.Error("failed to close log files with", err)
+	}
+
+	backups, _ := filepath.Glob(filepath.Join(dir, "test.0.log.*"))
+	if exist := len(backups); exist != 2 {
+		t.Error("failed to keep max backups. Got", backups)
+	}
+
+	for _, backup := range backups {
+		if !strings.HasSuffix(backup, compressExt) {
+			t.Error("failed to compress a backup", backup)
+			continue
+		}
+
+		file, _ := os.Open(backup)
+		reader, err := gzip.NewReader(file)
+		if err != nil {
+			t.Error("failed to read a compressed backup", backup, "with", err)
+			file.Close()
+			continue
+		}
+
+		if content, _ := ioutil.ReadAll(reader); string(content) != "0123456789\n" {
+			t.Error("unexpected content of a backup", backup, ". Got '", string(content), "'")
+		}
+		file.Close()
+	}
+
+	if info, err := os.Stat(files.Path(0)); err != nil || info.Size() != 11 {
+		t.Error("failed to write the current file with", err)
+	}
+}
+
+func TestLogFiles_RotateAge(t *testing.T) {
+	dir, err := ioutil.TempDir("", "logfiles")
+	if err != nil {
+		t.Error("failed to create a temp dir with", err)
+		return
+	}
+	defer os.RemoveAll(dir)
+
+	files := LogFiles(LogFileConfig{
+		Dir:    dir,
+		Name:   "test",
+		MaxAge: 50 * time.Millisecond,
+	})
+
+	sink := files.Sink("stdout")
+	sink.WriteLine(0, "line 1\n")
+	sink.WriteLine(0, "line 2\n")
+	time.Sleep(100 * time.Millisecond)
+	sink.WriteLine(0, "line 3\n")
+
+	if err := files.Close(); err != nil {
+		t.Error("failed to close log files with", err)
+	}
+
+	backups, _ := filepath.Glob(filepath.Join(dir, "test.0.log.*"))
+	if len(backups) != 1 {
+		t.Error("failed to rotate an old file. Got", backups)
+		return
+	}
+
+	if content, _ := ioutil.ReadFile(backups[0]); string(content) != "line 1\nline 2\n" {
+		t.Error("unexpected content of a backup. Got '", string(content), "'")
+	}
+
+	if content, _ := ioutil.ReadFile(files.Path(0)); string(content) != "line 3\n" {
+		t.Error("unexpected content of the current file. Got '", string(content), "'")
+	}
+}
@@ -0,0 +1,247 @@
+package monitoring
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	metricsNamespace   = "monitoring"
+	metricsContentType = "text/plain; version=0.0.4; charset=utf-8"
+)
+
+// lineCounter counts lines of an output of every instance.
+type lineCounter struct {
+	sync.Mutex
+
+	counts map[lineKey]uint64
+}
+
+type lineKey struct {
+	instance int
+	stream   string
+}
+
+func newLineCounter() *lineCounter {
+	return &lineCounter{
+		counts: make(map[lineKey]uint64),
+	}
+}
+
+func (o *lineCounter) sink(stream string) OutputSink {
+	return OutputSinkFunc(func(instance int, _ string) {
+		o.Lock()
+		o.counts[lineKey{instance: instance, stream: stream}]++
+		o.Unlock()
+	})
+}
+
+func (o *lineCounter) snapshot() map[lineKey]uint64 {
+	o.Lock()
+	defer o.Unlock()
+
+	counts := make(map[lineKey]uint64, len(o.counts))
+	for key, count := range o.counts {
+		counts[key] = count
+	}
+
+	return counts
+}
+
+type metricSample struct {
+	labels string
+	value  float64
+}
+
+type metricFamily struct {
+	name    string
+	help    string
+	kind    string
+	samples []metricSample
+}
+
+// MetricsCollector renders metrics of a Monitoring in the Prometheus text exposition format.
+// Labels are added to every sample to tell apart several programs served together.
+type MetricsCollector struct {
+	monitoring *Monitoring
+	labels     []string
+}
+
+func NewMetricsCollector(monitoring *Monitoring, labels map[string]string) *MetricsCollector {
+	collector := &MetricsCollector{
+		monitoring: monitoring,
+	}
+
+	for name, value := range labels {
+		collector.labels = append(collector.labels, formatLabel(name, value))
+	}
+	sort.Strings(collector.labels)
+
+	return collector
+}
+
+// WriteTo writes metrics of the Monitoring.
+func (o *MetricsCollector) WriteTo(w io.Writer) (int64, error) {
+	return writeMetrics(w, o.families())
+}
+
+func (o *MetricsCollector) families() []metricFamily {
+	var (
+		stage      = monitoringStage(atomic.LoadInt32(&o.monitoring.stage))
+		stages     = metricFamily{name: "stage", kind: "gauge", help: "Current stage of monitoring, 1 for the active one."}
+		restarts   = metricFamily{name: "instance_restarts_total", kind: "counter", help: "Restarts of an instance."}
+		uptime     = metricFamily{name: "instance_uptime_seconds", kind: "gauge", help: "Uptime of the current run of an instance, 0 if it has exited."}
+		exitCode   = metricFamily{name: "instance_last_exit_code", kind: "gauge", help: "Exit code of the last finished run of an instance, -1 if it was terminated by a signal."}
+		latency    = metricFamily{name: "instance_start_latency_seconds", kind: "gauge", help: "Time from a start of the last ready run of an instance till it has passed a start check."}
+		lines      = metricFamily{name: "output_lines_total", kind: "counter", help: "Lines of an output of an instance by streams."}
+		lineCounts = o.monitoring.lines.snapshot()
+	)
+
+	for s := execStopped; s <= execFinish; s++ {
+		value := 0.
+		if s == stage {
+			value = 1
+		}
+
+		stages.samples = append(stages.samples, o.sample(value, "stage", s.status()))
+	}
+
+	for _, cmd := range o.monitoring.commands() {
+		if cmd == nil {
+			continue
+		}
+
+		var (
+			status   = cmd.status()
+			instance = strconv.Itoa(cmd.index)
+		)
+
+		restart := 0.
+		if status.Runs > 1 {
+			restart = float64(status.Runs - 1)
+		}
+		restarts.samples = append(restarts.samples, o.sample(restart, "instance", instance))
+
+		up := 0.
+		if status.Runs > 0 && !status.Exited {
+			up = time.Since(status.StartTime).Seconds()
+		}
+		uptime.samples = append(uptime.samples, o.sample(up, "instance", instance))
+
+		if last, ok := cmd.exits.Last(); ok {
+			exitCode.samples = append(exitCode.samples, o.sample(float64(last.Code), "instance", instance))
+		}
+
+		if startLatency := cmd.StartLatency(); startLatency > 0 {
+			latency.samples = append(latency.samples, o.sample(startLatency.Seconds(), "instance", instance))
+		}
+	}
+
+	keys := make([]lineKey, 0, len(lineCounts))
+	for key := range lineCounts {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].instance != keys[j].instance {
+			return keys[i].instance < keys[j].instance
+		}
+
+		return keys[i].stream < keys[j].stream
+	})
+
+	for _, key := range keys {
+		lines.samples = append(lines.samples,
+			o.sample(float64(lineCounts[key]), "instance", strconv.Itoa(key.instance), "stream", key.stream))
+	}
+
+	return []metricFamily{stages, restarts, uptime, exitCode, latency, lines}
+}
+
+// sample adds labels of the collector to pairs of a name and a value.
+func (o *MetricsCollector) sample(value float64, labels ...string) metricSample {
+	pairs := append([]string(nil), o.labels...)
+	for i := 0; i+1 < len(labels); i += 2 {
+		pairs = append(pairs, formatLabel(labels[i], labels[i+1]))
+	}
+
+	return metricSample{
+		labels: strings.Join(pairs, ","),
+		value:  value,
+	}
+}
+
+func formatLabel(name, value string) string {
+	value = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(value)
+
+	return name + `="` + value + `"`
+}
+
+// writeMetrics writes families with the same name once, samples of several collectors are merged.
+func writeMetrics(w io.Writer, families []metricFamily) (int64, error) {
+	var (
+		buf    bytes.Buffer
+		order  []string
+		merged = make(map[string]*metricFamily)
+	)
+
+	for i := range families {
+		family := families[i]
+
+		if other, ok := merged[family.name]; ok {
+			other.samples = append(other.samples, family.samples...)
+			continue
+		}
+
+		order = append(order, family.name)
+		merged[family.name] = &family
+	}
+
+	for _, name := range order {
+		family := merged[name]
+		if len(family.samples) == 0 {
+			continue
+		}
+
+		fullName := metricsNamespace + "_" + family.name
+
+		fmt.Fprintf(&buf, "# HELP %s %s\n", fullName, family.help)
+		fmt.Fprintf(&buf, "# TYPE %s %s\n", fullName, family.kind)
+
+		for _, sample := range family.samples {
+			buf.WriteString(fullName)
+			if sample.labels != "" {
+				buf.WriteString("{" + sample.labels + "}")
+			}
+			buf.WriteString(" " + strconv.FormatFloat(sample.value, 'g', -1, 64) + "\n")
+		}
+	}
+
+	return buf.WriteTo(w)
+}
+
+// MetricsHandler serves metrics of all collectors for a Prometheus scrape.
+func MetricsHandler(collectors ...*MetricsCollector) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			w.Header().Set("Allow", http.MethodGet)
+			http.Error(w, "method "+r.Method+" is not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		var families []metricFamily
+		for _, collector := range collectors {
+			families = append(families, collector.families()...)
+		}
+
+		w.Header().Set("Content-Type", metricsContentType)
+		writeMetrics(w, families)
+	})
+}
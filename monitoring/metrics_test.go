@@ -0,0 +1,135 @@
+package monitoring
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestFormatLabel(t *testing.T) {
+	if exist := formatLabel("name", "a \"b\" \\c\nd"); exist != `name="a \"b\" \\c\nd"` {
+		t.Error("failed to escape a label. Got", exist, ", but expected is", `name="a \"b\" \\c\nd"`)
+	}
+}
+
+func TestMetricsCollector(t *testing.T) {
+	monitoring := NewMonitoring(&testParameter{
+		command:       "sh",
+		args:          []string{"-c", "if [ $" + EnvRun + " -gt 1 ]; then echo started; echo more; sleep 10; fi; echo started; sleep 0.2; exit 3"},
+		runningMode:   RepeatInfinity,
+		parallelCount: 1,
+	})
+
+	collector := NewMetricsCollector(monitoring, map[string]string{"program": "test"})
+
+	var buf bytes.Buffer
+	collector.WriteTo(&buf)
+
+	if metrics := buf.String(); !strings.Contains(metrics, `monitoring_stage{program="test",stage="stopped"} 1`) ||
+		strings.Contains(metrics, "monitoring_instance_restarts_total") {
+		t.Error("failed to collect metrics of stopped monitoring. Got", metrics)
+	}
+
+	events, unsubscribe := monitoring.Subscribe(100)
+	defer unsubscribe()
+
+	monitoring.Start(context.Background())
+	defer monitoring.Stop(context.Background())
+
+	// wait for the restarted run
+	for matched := 0; matched < 2; {
+		select {
+		case event := <-events:
+			if event.Type == EventStartLineMatched {
+				matched++
+			}
+
+		case <-time.After(5 * time.Second):
+			t.Fatal("failed to wait for a restart")
+		}
+	}
+
+	// lines of a run are counted after a start check
+	for start := time.Now(); time.Since(start) < 5*time.Second; time.Sleep(10 * time.Millisecond) {
+		if counts := monitoring.lines.snapshot(); counts[lineKey{instance: 0, stream: StreamStdOut}] == 3 {
+			break
+		}
+	}
+
+	buf.Reset()
+	collector.WriteTo(&buf)
+	metrics := buf.String()
+
+	for _, expected := range []string{
+		"# TYPE monitoring_stage gauge",
+		`monitoring_stage{program="test",stage="monitoring"} 1`,
+		`monitoring_stage{program="test",stage="stopped"} 0`,
+		"# TYPE monitoring_instance_restarts_total counter",
+		`monitoring_instance_restarts_total{program="test",instance="0"} 1`,
+		`monitoring_instance_last_exit_code{program="test",instance="0"} 3`,
+		`monitoring_instance_uptime_seconds{program="test",instance="0"} `,
+		`monitoring_instance_start_latency_seconds{program="test",instance="0"} `,
+		`monitoring_output_lines_total{program="test",instance="0",stream="stdout"} 3`,
+	} {
+		if !strings.Contains(metrics, expected) {
+			t.Error("failed to collect a metric", expected, ". Got", metrics)
+		}
+	}
+
+	if strings.Contains(metrics, `stream="stderr"`) {
+		t.Error("failed to skip an empty stream. Got", metrics)
+	}
+}
+
+func TestMetricsHandler(t *testing.T) {
+	var (
+		first  = NewMonitoring(&testParameter{command: "sh", args: []string{"-c", "echo started"}})
+		second = NewMonitoring(&testParameter{command: "sh", args: []string{"-c", "echo started"}})
+		server = httptest.NewServer(MetricsHandler(
+			NewMetricsCollector(first, map[string]string{"program": "first"}),
+			NewMetricsCollector(second, map[string]string{"program": "second"}),
+		))
+	)
+	defer server.Close()
+
+	resp, err := http.Get(server.URL)
+	if err != nil {
+		t.Fatal("failed to request metrics with", err)
+	}
+	defer resp.Body.Close()
+
+	body, _ := ioutil.ReadAll(resp.Body)
+	metrics := string(body)
+
+	if contentType := resp.Header.Get("Content-Type"); contentType != metricsContentType {
+		t.Error("failed to set a content type. Got", contentType, ", but expected is", metricsContentType)
+	}
+
+	if count := strings.Count(metrics, "# TYPE monitoring_stage gauge"); count != 1 {
+		t.Error("failed to merge metrics of collectors. Got", count, "headers, but expected is", 1)
+	}
+
+	for _, expected := range []string{
+		`monitoring_stage{program="first",stage="stopped"} 1`,
+		`monitoring_stage{program="second",stage="stopped"} 1`,
+	} {
+		if !strings.Contains(metrics, expected) {
+			t.Error("failed to collect a metric", expected, ". Got", metrics)
+		}
+	}
+
+	resp, err = http.Post(server.URL, "text/plain", nil)
+	if err != nil {
+		t.Fatal("failed to request metrics with", err)
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode != http.StatusMethodNotAllowed {
+		t.Error("failed to reject a method. Got", resp.StatusCode, ", but expected is", http.StatusMethodNotAllowed)
+	}
+}
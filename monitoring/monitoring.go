@@ -6,8 +6,10 @@ package monitoring
 import (
 	"context"
 	"fmt"
+	"io"
 	"sync"
 	"sync/atomic"
+	"time"
 
 	"github.com/7phs/tools/common"
 	"github.com/pkg/errors"
@@ -34,6 +36,11 @@ const (
 	cmdStart monitoringCommand = iota
 	cmdKill
 	cmdStop
+	cmdStartInstance
+	cmdStopInstance
+	cmdRestartInstance
+	cmdRollingRestart
+	cmdScale
 )
 
 const (
@@ -53,8 +60,18 @@ type MonitoringParameter interface {
 type Monitoring struct {
 	MonitoringParameter
 
-	cmd []*commandState
-	err error
+	cmd     []*commandState
+	cmdLock sync.RWMutex
+	err     error
+
+	events eventBus
+	ports  *portAllocator
+
+	stdoutSink OutputSink
+	stderrSink OutputSink
+	sinkCloser io.Closer
+	tail       *outputTail
+	lines      *lineCounter
 
 	stage int32
 
@@ -64,6 +81,7 @@ type Monitoring struct {
 	monitoring      chan interface{}
 	monitoringState bool
 	monitoringWait  sync.WaitGroup
+	finished        int32
 }
 
 func NewMonitoring(parameter MonitoringParameter) *Monitoring {
@@ -77,6 +95,13 @@ func NewMonitoring(parameter MonitoringParameter) *Monitoring {
 func (o *Monitoring) init() *Monitoring {
 	atomic.StoreInt32(&o.stage, execStopped.Int32())
 
+	o.stdoutSink, o.stderrSink, o.sinkCloser = outputSinks(o.MonitoringParameter)
+
+	o.tail = newOutputTail(defaultTailSize)
+	o.lines = newLineCounter()
+	o.stdoutSink = MultiSink(o.stdoutSink, o.tail.sink(StreamStdOut), o.lines.sink(StreamStdOut))
+	o.stderrSink = MultiSink(o.stderrSink, o.tail.sink(StreamStdErr), o.lines.sink(StreamStdErr))
+
 	o.commandWait.Add(1)
 	go o.commandFlowExecution()
 
@@ -126,6 +151,25 @@ func (o *Monitoring) HasError() error {
 	return o.err
 }
 
+// isRunning reports commands are monitored, it is nil-safe.
+func (o *Monitoring) isRunning() bool {
+	return o != nil && atomic.LoadInt32(&o.stage) == execMonitoring.Int32()
+}
+
+// Subscribe returns a channel of lifecycle events with a buffer of the size and a function
+// to unsubscribe. Events are dropped while the buffer is full, so read it fast.
+func (o *Monitoring) Subscribe(size int) (<-chan Event, func()) {
+	return o.events.subscribe(size)
+}
+
+func (o *Monitoring) emit(eventType EventType, err error) {
+	o.events.emit(Event{
+		Type:     eventType,
+		Instance: monitoringInstance,
+		Err:      err,
+	})
+}
+
 func (o *Monitoring) commandFlowExecution() {
 	var (
 		completely = false
@@ -168,11 +212,22 @@ func (o *Monitoring) commandExecution(ctx context.Context) (finish bool, err err
 	case cmdStop:
 		finish, err = o.commandStop(ctx)
 
+	case cmdStartInstance, cmdStopInstance, cmdRestartInstance, cmdRollingRestart:
+		// an error of an instance is reported to a caller only
+		doneErr = o.commandInstance(ctx, command)
+		return
+
+	case cmdScale:
+		doneErr = o.commandScale(ctx)
+		return
+
 	default:
 		err = errors.New(fmt.Sprint("unknown command:", command))
 	}
 
-	o.catchError(err)
+	if o.catchError(err) != nil {
+		o.emit(EventError, err)
+	}
 
 	doneErr = err
 
@@ -184,20 +239,8 @@ func (o *Monitoring) commandStart(ctx context.Context) (finish bool, err error)
 		return false, errors.New("failed to start execute command start - already started")
 	}
 
-	if o.cmd == nil || len(o.cmd) == 0 {
-		parallelCount := o.ParallelCount()
-		if parallelCount <= 0 {
-			parallelCount = 1
-		}
-
-		o.cmd = make([]*commandState, parallelCount)
-	}
-
-	for i := range o.cmd {
-		o.cmd[i], err = CommandState(o)
-		if err != nil {
-			return true, errors.Wrapf(err, "failed to create command for monitoring")
-		}
+	if err = o.prepareCommands(); err != nil {
+		return true, err
 	}
 
 	wait := make(chan error)
@@ -215,13 +258,88 @@ func (o *Monitoring) commandStart(ctx context.Context) (finish bool, err error)
 	if err == nil {
 		err = o.startMonitoring()
 	} else {
-		o.killAll()
+		o.killAll(ctx)
 	}
 
 	return err != nil, err
 }
 
-func (o *Monitoring) killAll() {
+func (o *Monitoring) prepareCommands() (err error) {
+	o.cmdLock.Lock()
+	defer o.cmdLock.Unlock()
+
+	if o.cmd == nil || len(o.cmd) == 0 {
+		parallelCount := o.ParallelCount()
+		if parallelCount <= 0 {
+			parallelCount = 1
+		}
+
+		o.cmd = make([]*commandState, parallelCount)
+	}
+
+	if o.ports, err = newPortAllocator(o.MonitoringParameter); err != nil {
+		return errors.Wrap(err, "failed to allocate ports for monitoring")
+	}
+
+	for i := range o.cmd {
+		if o.cmd[i], err = o.newCommand(i); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (o *Monitoring) newCommand(index int) (*commandState, error) {
+	cmd, err := CommandState(o.MonitoringParameter)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to create command for monitoring")
+	}
+
+	cmd.index = index
+	cmd.events = &o.events
+	cmd.ports = o.ports
+	cmd.stdoutSink, cmd.stderrSink = o.stdoutSink, o.stderrSink
+
+	return cmd, nil
+}
+
+func (o *Monitoring) commands() []*commandState {
+	o.cmdLock.RLock()
+	defer o.cmdLock.RUnlock()
+
+	return append([]*commandState{}, o.cmd...)
+}
+
+// ExitHistory returns last exit records of every parallel instance since the last start.
+func (o *Monitoring) ExitHistory() [][]ExitRecord {
+	cmds := o.commands()
+	history := make([][]ExitRecord, len(cmds))
+
+	for i, cmd := range cmds {
+		if cmd != nil {
+			history[i] = cmd.ExitHistory()
+		}
+	}
+
+	return history
+}
+
+// Ports returns ports assigned to every parallel instance, zeros without PortParameter.
+func (o *Monitoring) Ports() []int {
+	cmds := o.commands()
+	ports := make([]int, len(cmds))
+
+	for i, cmd := range cmds {
+		if cmd != nil {
+			ports[i] = cmd.Port()
+		}
+	}
+
+	return ports
+}
+
+func (o *Monitoring) killAll(ctx context.Context) error {
 	var wait sync.WaitGroup
 
 	if o.monitoringState {
@@ -229,18 +347,48 @@ func (o *Monitoring) killAll() {
 		o.monitoringState = false
 	}
 
-	for _, cmd := range o.cmd {
+	errs := make([]error, len(o.cmd))
+
+	for i, cmd := range o.cmd {
 		wait.Add(1)
-		go func(cmd *commandState) {
-			if cmd != nil && !cmd.IsExited() {
-				cmd.Kill()
+		go func(i int, cmd *commandState) {
+			defer wait.Done()
+
+			if cmd == nil {
+				return
+			}
+
+			// a restart of the instance is over before a kill, the next one is skipped after it
+			cmd.control.Lock()
+			defer cmd.control.Unlock()
+
+			if cmd.isHeld() {
+				return
+			}
+
+			// the group of a finished instance was killed at its exit
+			if cmd.isFinished() {
+				return
 			}
 
-			wait.Done()
-		}(cmd)
+			defer cmd.emit(EventKilled, nil)
+
+			// an error of hard killing is not interesting, only an outcome of graceful stop,
+			// a status after a stop reports the exit
+			if cmd.stopSignal == nil {
+				if cmd.Kill() == nil {
+					<-cmd.Wait()
+				}
+				return
+			}
+
+			errs[i] = errors.Wrapf(cmd.Terminate(ctx), "instance #%d", i)
+		}(i, cmd)
 	}
 
 	wait.Wait()
+
+	return common.SeveralErrors("failed to stop commands", errs...)
 }
 
 func (o *Monitoring) startMonitoring() error {
@@ -250,29 +398,56 @@ func (o *Monitoring) startMonitoring() error {
 
 	o.monitoring = make(chan interface{})
 	o.monitoringState = true
+	atomic.StoreInt32(&o.finished, 0)
 
 	for _, cmd := range o.cmd {
-		go o.monitoringProcess(cmd)
+		o.monitorInstance(cmd)
+		o.watchInstance(cmd)
 	}
 
 	return nil
 }
 
+// watchInstance starts optional checks of an instance, they are over with monitoring or a removal of the instance.
+func (o *Monitoring) watchInstance(cmd *commandState) {
+	removed, stop := make(chan interface{}), make(chan interface{})
+	cmd.removed = removed
+
+	go func(monitoring <-chan interface{}) {
+		defer close(stop)
+
+		select {
+		case <-monitoring:
+		case <-removed:
+		}
+	}(o.monitoring)
+
+	if health, ok := o.MonitoringParameter.(HealthParameter); ok && health.HealthCheck() != nil {
+		go o.healthProcess(cmd, health.HealthCheck().withDefaults(), stop)
+	}
+
+	if interval, limits := o.resourceSampling(); interval > 0 {
+		go o.resourceProcess(cmd, interval, limits, stop)
+	}
+
+	if config := o.lifetimeConfig(); config != nil && config.MaxLifetime > 0 {
+		go o.lifetimeProcess(cmd, *config, stop)
+	}
+}
+
 func (o *Monitoring) commandKill(ctx context.Context) (finish bool, err error) {
 	if !atomic.CompareAndSwapInt32(&o.stage, execMonitoring.Int32(), execStopped.Int32()) {
 		return false, errors.New("failed to execute command kill - already stopped")
 	}
 
-	wait := make(chan interface{})
+	wait := make(chan error, 1)
 
 	go func() {
-		o.killAll()
-
-		close(wait)
+		wait <- o.killAll(ctx)
 	}()
 
 	select {
-	case <-wait:
+	case err = <-wait:
 
 	case <-ctx.Done():
 		err = errors.New("user cancel")
@@ -284,28 +459,48 @@ func (o *Monitoring) commandKill(ctx context.Context) (finish bool, err error) {
 func (o *Monitoring) commandStop(ctx context.Context) (finish bool, err error) {
 	atomic.StoreInt32(&o.stage, execFinish.Int32())
 
-	wait := make(chan interface{})
+	wait := make(chan error, 1)
 
 	go func() {
-		o.killAll()
-
-		close(wait)
+		wait <- o.killAll(ctx)
 	}()
 
 	select {
-	case <-wait:
+	case err = <-wait:
 
 	case <-ctx.Done():
 		err = errors.New("user cancel")
 	}
 
+	if o.sinkCloser != nil {
+		err = common.SeveralErrors("failed to stop", err, o.sinkCloser.Close())
+	}
+
+	o.emit(EventStopped, nil)
+
 	return true, err
 }
 
-func (o *Monitoring) monitoringProcess(cmd *commandState) {
+// monitoringProcess restarts an instance till monitoring is over or StopInstance leaves the instance.
+// It never waits for the command flow, a command of the flow could wait for it in turn.
+func (o *Monitoring) monitoringProcess(cmd *commandState, leave <-chan struct{}) {
 	repeat := o.RunningMode()
 	wait := make(chan error)
 
+	// a restart is cancelled with monitoring or by StopInstance, so neither waits for its start check
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	go func(monitoring <-chan interface{}) {
+		select {
+		case <-monitoring:
+			cancel()
+		case <-leave:
+			cancel()
+		case <-ctx.Done():
+		}
+	}(o.monitoring)
+
 	for {
 		select {
 		case <-cmd.Wait():
@@ -314,18 +509,116 @@ func (o *Monitoring) monitoringProcess(cmd *commandState) {
 			return
 		}
 
+		// children of the leader are killed with it, the next run starts a new group
+		cmd.killGroup()
+
+		o.events.emit(Event{
+			Type:     EventExited,
+			Instance: cmd.index,
+			Pid:      cmd.Pid(),
+			Exit:     cmd.exitRecord(),
+		})
+
+		// stopped by StopInstance
+		if cmd.isHeld() {
+			return
+		}
+
+		if o.runsExhausted(cmd) {
+			cmd.recycleRuns()
+			cmd.emit(EventExpired, nil)
+		}
+
+		if !o.needRestart(cmd) {
+			o.finishInstance(cmd)
+			return
+		}
+
 		switch {
 		case repeat == RepeatInfinity, cmd.RunCount() <= repeat:
-			cmd.Init(o)
-			cmd.Run(context.Background(), wait)
-			if o.catchError(<-wait) != nil {
-				o.Stop(context.Background())
+			cmd.emit(EventRestarting, nil)
+
+			if !o.waitRestart(cmd, leave) {
+				return
+			}
+
+			// a command of the instance or a stop of monitoring could stop it while waiting
+			cmd.control.Lock()
+			if cmd.isHeld() || !o.isRunning() {
+				cmd.control.Unlock()
+				return
+			}
+
+			cmd.Init(o.MonitoringParameter)
+			go cmd.Run(ctx, wait)
+			err := <-wait
+			cmd.control.Unlock()
+
+			// killAll kills the run after a stop of monitoring, StopInstance does it after leaving
+			if !o.isRunning() {
+				return
+			}
+
+			select {
+			case <-leave:
+				return
+			default:
+			}
+
+			if o.catchError(err) != nil {
+				go o.Stop(context.Background())
 				return
 			}
 
 		case repeat == RunOnce, cmd.RunCount() > repeat:
-			o.Stop(context.Background())
+			go o.Stop(context.Background())
 			return
 		}
 	}
 }
+
+func (o *Monitoring) needRestart(cmd *commandState) bool {
+	parameter, ok := o.MonitoringParameter.(RestartRuleParameter)
+	if !ok || cmd.IsUnhealthy() || cmd.isRecycled() {
+		return true
+	}
+
+	code, sig := cmd.exitStatus()
+
+	return parameter.RestartMode().NeedRestart(code, sig, parameter.SuccessExitCodes()...)
+}
+
+// finishInstance stops monitoring when every instance has finished without a restart.
+func (o *Monitoring) finishInstance(cmd *commandState) {
+	atomic.StoreInt32(&cmd.finished, 1)
+
+	if int(atomic.AddInt32(&o.finished, 1)) >= len(o.commands()) {
+		go o.Stop(context.Background())
+	}
+}
+
+func (o *Monitoring) waitRestart(cmd *commandState, leave <-chan struct{}) bool {
+	parameter, ok := o.MonitoringParameter.(RestartParameter)
+	if !ok || parameter.RestartPolicy() == nil {
+		return true
+	}
+
+	delay := cmd.restartDelay(parameter.RestartPolicy())
+	if delay <= 0 {
+		return true
+	}
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return true
+
+	case <-o.monitoring:
+		return false
+
+	case <-leave:
+		return false
+	}
+}
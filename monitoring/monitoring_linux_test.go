@@ -0,0 +1,59 @@
+//go:build linux
+// +build linux
+
+package monitoring
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestMonitoring_StopRestarting(t *testing.T) {
+	monitoring := NewMonitoring(&testParameter{
+		command:       "sh",
+		args:          []string{"-c", "if [ $" + EnvRun + " -gt 1 ]; then sleep 0.5; echo started; sleep 10; fi; echo started; sleep 0.2; exit 1"},
+		runningMode:   RepeatInfinity,
+		parallelCount: 1,
+	})
+
+	events, unsubscribe := monitoring.Subscribe(100)
+	defer unsubscribe()
+
+	monitoring.Start(context.Background())
+
+	// the second run waits for a start line
+	var pids []int
+	for len(pids) < 2 {
+		select {
+		case event := <-events:
+			if event.Type == EventStarted {
+				pids = append(pids, event.Pid)
+			}
+
+		case <-time.After(5 * time.Second):
+			t.Fatal("failed to wait for a restart")
+		}
+	}
+
+	start := time.Now()
+
+	monitoring.Stop(context.Background())
+	monitoring.Wait()
+
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Error("failed to interrupt a start check of a restart. Got", elapsed)
+	}
+
+	if err := monitoring.HasError(); err != nil {
+		t.Error("failed to stop monitoring while restarting with", err)
+	}
+
+	time.Sleep(time.Second)
+
+	if _, err := os.Stat(fmt.Sprintf("/proc/%d", pids[1])); !os.IsNotExist(err) {
+		t.Error("failed to kill a run started while stopping. Got", err)
+	}
+}
@@ -2,13 +2,20 @@ package monitoring
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"io/ioutil"
 	"math/rand"
+	"net"
+	"os"
+	"path/filepath"
+	"regexp"
 	"strconv"
 	"strings"
 	"testing"
 	"time"
 	"sync"
+	"syscall"
 )
 
 func TestMonitoringCommand_String(t *testing.T) {
@@ -422,3 +429,609 @@ func TestMonitoring_StopCancellation(t *testing.T) {
 		t.Error("failed to catch cancelation in stop command monitoring with", err)
 	}
 }
+
+func TestMonitoring_RestartPolicy(t *testing.T) {
+	delay := 100 * time.Millisecond
+	runCount := int32(3)
+
+	monitoring := NewMonitoring(&testParameter{
+		command:       "echo",
+		args:          []string{"hello world"},
+		runningMode:   runCount,
+		restartPolicy: ConstantRestart(delay),
+	})
+
+	start := time.Now()
+	monitoring.Start(context.Background())
+
+	if err := monitoring.HasError(); err != nil {
+		t.Error("failed to start command monitoring with", err)
+	}
+
+	monitoring.Wait()
+	exist := time.Since(start)
+
+	if err := monitoring.HasError(); err != nil {
+		t.Error("failed to run command monitoring with", err)
+	}
+
+	if expected := time.Duration(runCount) * delay; exist < expected {
+		t.Error("failed to wait between restarts. Executed", exist, ", but expected at least", expected)
+	}
+}
+
+func TestMonitoring_StopGracefully(t *testing.T) {
+	monitoring := NewMonitoring(&testParameter{
+		command:       "sh",
+		args:          []string{"-c", "trap 'exit 0' TERM; echo started; while true; do sleep 0.1; done"},
+		parallelCount: 3,
+		stopSignal:    syscall.SIGTERM,
+		stopTimeout:   time.Second,
+	})
+
+	monitoring.Start(context.Background())
+
+	if err := monitoring.HasError(); err != nil {
+		t.Error("failed to start command monitoring with", err)
+	}
+
+	ctx, _ := CommandCtx(context.Background(), cmdStop)
+
+	monitoring.Stop(ctx)
+	monitoring.Wait()
+
+	if err := ctx.(*commandCtx).HasError(); err != nil {
+		t.Error("failed to stop command monitoring gracefully with", err)
+	}
+}
+
+func TestMonitoring_StopKillAfter(t *testing.T) {
+	monitoring := NewMonitoring(&testParameter{
+		command:       "sh",
+		args:          []string{"-c", "trap '' TERM; echo started; while true; do sleep 0.1; done"},
+		parallelCount: 3,
+		stopSignal:    syscall.SIGTERM,
+		stopTimeout:   100 * time.Millisecond,
+	})
+
+	monitoring.Start(context.Background())
+
+	if err := monitoring.HasError(); err != nil {
+		t.Error("failed to start command monitoring with", err)
+	}
+
+	ctx, _ := CommandCtx(context.Background(), cmdStop)
+
+	monitoring.Stop(ctx)
+	monitoring.Wait()
+
+	if err := ctx.(*commandCtx).HasError(); err == nil {
+		t.Error("failed to report killing after the stop timeout")
+	}
+}
+
+func TestMonitoring_ExitHistory(t *testing.T) {
+	runCount := int32(2)
+
+	monitoring := NewMonitoring(&testParameter{
+		command:     "sh",
+		args:        []string{"-c", "exit 3"},
+		runningMode: runCount,
+	})
+
+	monitoring.Start(context.Background())
+	monitoring.Wait()
+
+	if err := monitoring.HasError(); err != nil {
+		t.Error("failed to run command monitoring with", err)
+	}
+
+	time.Sleep(50 * time.Millisecond)
+
+	history := monitoring.ExitHistory()
+	if exist := len(history); exist != 1 {
+		t.Error("failed to get history of every instance. Got", exist)
+		return
+	}
+
+	for i, records := range history {
+		if exist, expected := len(records), int(runCount)+1; exist != expected {
+			t.Error("failed to record every exit of instance #", i, ". Got", exist, ", but expected is", expected)
+		}
+
+		for j, record := range records {
+			if record.Instance != i || record.Run != int32(j+1) || record.Code != 3 || record.Signal != nil || record.Success() {
+				t.Error("unexpected exit record of instance #", i, ":", record)
+			}
+
+			if record.StartTime.IsZero() || record.EndTime.Before(record.StartTime) {
+				t.Error("failed to record start and end time of instance #", i, ":", record)
+			}
+		}
+	}
+}
+
+func TestMonitoring_ExitHistoryKill(t *testing.T) {
+	monitoring := NewMonitoring(&testParameter{
+		command: "sh",
+		args:    []string{"-c", "echo started; sleep 5"},
+	})
+
+	monitoring.Start(context.Background())
+	monitoring.Stop(context.Background())
+	monitoring.Wait()
+
+	time.Sleep(50 * time.Millisecond)
+
+	history := monitoring.ExitHistory()
+	if len(history) != 1 || len(history[0]) != 1 {
+		t.Error("failed to record a killed command. Got", history)
+		return
+	}
+
+	if record := history[0][0]; record.Signal != syscall.SIGKILL || record.Code != -1 {
+		t.Error("failed to record a signal of a killed command. Got", record)
+	}
+}
+
+func TestMonitoring_RestartOnFailure(t *testing.T) {
+	suites := []struct {
+		command  string
+		success  []int
+		expected int32
+	}{
+		{"exit 0", nil, 1},
+		{"exit 3", []int{3}, 1},
+		{"exit 1", nil, RepeatInfinity},
+	}
+
+	for _, test := range suites {
+		monitoring := NewMonitoring(&testParameter{
+			command:       "sh",
+			args:          []string{"-c", test.command},
+			parallelCount: 2,
+			restartMode:   RestartOnFailure,
+			successCodes:  test.success,
+		})
+
+		monitoring.Start(context.Background())
+
+		finished := make(chan bool)
+		go func() {
+			monitoring.Wait()
+			close(finished)
+		}()
+
+		select {
+		case <-finished:
+			if test.expected == RepeatInfinity {
+				t.Error("failed to restart a failed command '", test.command, "'")
+			}
+
+		case <-time.After(300 * time.Millisecond):
+			if test.expected != RepeatInfinity {
+				t.Error("failed to finish a successful command '", test.command, "'")
+			}
+
+			monitoring.Stop(context.Background())
+			monitoring.Wait()
+		}
+
+		if err := monitoring.HasError(); err != nil {
+			t.Error("failed to run command '", test.command, "' monitoring with", err)
+		}
+
+		for _, cmd := range monitoring.commands() {
+			if exist := cmd.RunCount(); test.expected != RepeatInfinity && exist != test.expected {
+				t.Error("unexpected run count of command '", test.command, "'. Got", exist, ", but expected is", test.expected)
+			}
+		}
+	}
+}
+
+func TestMonitoring_Subscribe(t *testing.T) {
+	monitoring := NewMonitoring(&testParameter{
+		command:     "echo",
+		args:        []string{"hello world"},
+		runningMode: 1,
+	})
+
+	events, unsubscribe := monitoring.Subscribe(100)
+	defer unsubscribe()
+
+	monitoring.Start(context.Background())
+	monitoring.Wait()
+
+	var exist []EventType
+	for event := range events {
+		if event.Instance != monitoringInstance && event.Pid == 0 && event.Type != EventStarting {
+			t.Error("failed to set a pid of an event", event.Type)
+		}
+
+		if event.Type == EventExited && (event.Exit == nil || event.Exit.Code != 0) {
+			t.Error("failed to set an exit record of an event. Got", event.Exit)
+		}
+
+		exist = append(exist, event.Type)
+		if event.Type == EventStopped {
+			break
+		}
+	}
+
+	expected := []EventType{
+		EventStarting, EventStarted, EventStartLineMatched, EventExited,
+		EventRestarting, EventStarting, EventStarted, EventStartLineMatched, EventExited,
+		EventStopped,
+	}
+
+	if len(exist) != len(expected) {
+		t.Error("unexpected events. Got", exist, ", but expected is", expected)
+		return
+	}
+
+	for i := range expected {
+		if exist[i] != expected[i] {
+			t.Error("unexpected events. Got", exist, ", but expected is", expected)
+			return
+		}
+	}
+}
+
+func TestMonitoring_OutputSink(t *testing.T) {
+	const lineCount = 20000
+
+	var (
+		lock   sync.Mutex
+		stdout = map[int]int{}
+		stderr = map[int]int{}
+	)
+
+	counter := func(counts map[int]int) OutputSink {
+		return OutputSinkFunc(func(instance int, line string) {
+			lock.Lock()
+			counts[instance]++
+			lock.Unlock()
+		})
+	}
+
+	monitoring := NewMonitoring(&testParameter{
+		command: "sh",
+		args: []string{"-c", fmt.Sprintf(
+			"echo started; i=1; while [ $i -lt %d ]; do echo line $i; echo error $i >&2; i=$((i+1)); done; sleep 0.5",
+			lineCount)},
+		runningMode:   RunOnce,
+		parallelCount: 2,
+		stdErrIsOk:    true,
+		stdoutSink:    counter(stdout),
+		stderrSink:    counter(stderr),
+	})
+
+	monitoring.Start(context.Background())
+
+	finished := make(chan bool)
+	go func() {
+		monitoring.Wait()
+		close(finished)
+	}()
+
+	select {
+	case <-finished:
+	case <-time.After(10 * time.Second):
+		t.Error("failed to finish a command with a big output, it is stalled on a full pipe")
+		monitoring.Stop(context.Background())
+		return
+	}
+
+	if err := monitoring.HasError(); err != nil {
+		t.Error("failed to run command monitoring with", err)
+	}
+
+	lock.Lock()
+	defer lock.Unlock()
+
+	for instance := 0; instance < 2; instance++ {
+		if exist := stdout[instance]; exist != lineCount {
+			t.Error("failed to forward stdout of instance #", instance, ". Got", exist, "lines, but expected is", lineCount)
+		}
+
+		if exist := stderr[instance]; exist != lineCount-1 {
+			t.Error("failed to forward stderr of instance #", instance, ". Got", exist, "lines, but expected is", lineCount-1)
+		}
+	}
+}
+
+func TestMonitoring_LogFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "monitoring")
+	if err != nil {
+		t.Error("failed to create a temp dir with", err)
+		return
+	}
+	defer os.RemoveAll(dir)
+
+	monitoring := NewMonitoring(&testParameter{
+		command:       "sh",
+		args:          []string{"-c", "echo started; echo failed >&2; sleep 0.2"},
+		runningMode:   RunOnce,
+		parallelCount: 2,
+		stdErrIsOk:    true,
+		logFile: &LogFileConfig{
+			Dir:    dir,
+			Name:   "sh",
+			Prefix: PrefixStream,
+		},
+	})
+
+	monitoring.Start(context.Background())
+	monitoring.Wait()
+
+	if err := monitoring.HasError(); err != nil {
+		t.Error("failed to run command monitoring with", err)
+	}
+
+	for instance := 0; instance < 2; instance++ {
+		content, err := ioutil.ReadFile(filepath.Join(dir, fmt.Sprintf("sh.%d.log", instance)))
+		if err != nil {
+			t.Error("failed to read a log file of instance #", instance, "with", err)
+			continue
+		}
+
+		if exist := string(content); !strings.Contains(exist, "[stdout] started\n") || !strings.Contains(exist, "[stderr] failed\n") {
+			t.Error("unexpected log of instance #", instance, ". Got '", exist, "'")
+		}
+	}
+}
+
+func TestMonitoring_HealthCheck(t *testing.T) {
+	var unhealthyPid int
+
+	monitoring := NewMonitoring(&testParameter{
+		command:     "sh",
+		args:        []string{"-c", "echo started; sleep 10"},
+		restartMode: RestartOnFailure,
+		stopSignal:  syscall.SIGTERM,
+		healthCheck: &HealthConfig{
+			Check: HealthCheckFunc(func(ctx context.Context, instance Instance) error {
+				if unhealthyPid == 0 {
+					unhealthyPid = instance.Pid
+				}
+
+				if instance.Pid == unhealthyPid {
+					return errors.New("unhealthy")
+				}
+
+				return nil
+			}),
+			Interval:         20 * time.Millisecond,
+			FailureThreshold: 3,
+		},
+	})
+
+	events, unsubscribe := monitoring.Subscribe(100)
+	defer unsubscribe()
+
+	monitoring.Start(context.Background())
+
+	if err := monitoring.HasError(); err != nil {
+		t.Error("failed to start command monitoring with", err)
+	}
+
+	var exist []EventType
+
+	timeout := time.After(2 * time.Second)
+	for len(exist) == 0 || exist[len(exist)-1] != EventStartLineMatched || len(exist) < 4 {
+		select {
+		case event := <-events:
+			exist = append(exist, event.Type)
+		case <-timeout:
+			t.Error("failed to restart an unhealthy instance. Got events", exist)
+			monitoring.Stop(context.Background())
+			return
+		}
+	}
+
+	expected := []EventType{EventStarting, EventStarted, EventStartLineMatched, EventUnhealthy, EventExited, EventRestarting, EventStarting, EventStarted, EventStartLineMatched}
+	if len(exist) != len(expected) {
+		t.Error("unexpected events. Got", exist, ", but expected is", expected)
+	}
+
+	monitoring.Stop(context.Background())
+	monitoring.Wait()
+
+	if exist := monitoring.commands()[0].RunCount(); exist != 2 {
+		t.Error("failed to restart an unhealthy instance once. Got run count", exist)
+	}
+}
+
+func TestMonitoring_Readiness(t *testing.T) {
+	dir, err := ioutil.TempDir("", "monitoring")
+	if err != nil {
+		t.Error("failed to create a temp dir with", err)
+		return
+	}
+	defer os.RemoveAll(dir)
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Error("failed to listen with", err)
+		return
+	}
+	address := ln.Addr().String()
+	ln.Close()
+
+	readyFile := filepath.Join(dir, "ready")
+	delay := 200 * time.Millisecond
+
+	suites := []struct {
+		name      string
+		args      []string
+		readiness *ReadinessConfig
+		listen    bool
+	}{
+		{"file", []string{"-c", "sleep 0.2; touch " + readyFile + "; sleep 5"}, &ReadinessConfig{Check: FileCheck(readyFile)}, false},
+		{"stderr", []string{"-c", "echo starting >&2; sleep 0.2; echo listening >&2; sleep 5"}, &ReadinessConfig{StdErr: regexp.MustCompile(`^listening`)}, false},
+		{"tcp", []string{"-c", "sleep 5"}, &ReadinessConfig{Check: TCPCheck(address), Interval: 20 * time.Millisecond}, true},
+	}
+
+	for _, test := range suites {
+		if test.listen {
+			go func() {
+				time.Sleep(delay)
+				if ln, err := net.Listen("tcp", address); err == nil {
+					time.Sleep(time.Second)
+					ln.Close()
+				}
+			}()
+		}
+
+		monitoring := NewMonitoring(&testParameter{
+			command:   "sh",
+			args:      test.args,
+			readiness: test.readiness,
+		})
+
+		ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+
+		start := time.Now()
+		monitoring.Start(ctx)
+		exist := time.Since(start)
+
+		if err := monitoring.HasError(); err != nil {
+			t.Error("failed to start command monitoring with", test.name, "readiness with", err)
+		}
+
+		if exist < delay {
+			t.Error("failed to wait for", test.name, "readiness. Started in", exist)
+		}
+
+		monitoring.Stop(context.Background())
+		monitoring.Wait()
+		cancel()
+	}
+}
+
+func TestMonitoring_ReadinessFailure(t *testing.T) {
+	suites := []struct {
+		name string
+		args []string
+	}{
+		{"timeout", []string{"-c", "sleep 5"}},
+		{"exit", []string{"-c", "exit 1"}},
+	}
+
+	for _, test := range suites {
+		monitoring := NewMonitoring(&testParameter{
+			command:   "sh",
+			args:      test.args,
+			readiness: &ReadinessConfig{Check: FileCheck("/unknown/ready")},
+		})
+
+		ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+
+		monitoring.Start(ctx)
+		monitoring.Wait()
+
+		if err := monitoring.HasError(); err == nil {
+			t.Error("failed to catch an error of", test.name, "readiness")
+		}
+
+		cancel()
+	}
+}
+
+func TestMonitoring_InstanceEnv(t *testing.T) {
+	var (
+		lock  sync.Mutex
+		exist = map[int]string{}
+	)
+
+	monitoring := NewMonitoring(&testParameter{
+		command:       "sh",
+		args:          []string{"-c", "echo $" + EnvInstance + " $" + EnvRun + " $TEST_ENV_VALUE; sleep 0.2"},
+		runningMode:   RunOnce,
+		parallelCount: 3,
+		env:           []string{"TEST_ENV_VALUE=value"},
+		stdoutSink: OutputSinkFunc(func(instance int, line string) {
+			lock.Lock()
+			exist[instance] = line
+			lock.Unlock()
+		}),
+	})
+
+	monitoring.Start(context.Background())
+	monitoring.Wait()
+
+	if err := monitoring.HasError(); err != nil {
+		t.Error("failed to run command monitoring with", err)
+	}
+
+	lock.Lock()
+	defer lock.Unlock()
+
+	for instance := 0; instance < 3; instance++ {
+		if expected := fmt.Sprintf("%d 1 value\n", instance); exist[instance] != expected {
+			t.Error("failed to pass an environment of instance #", instance, ". Got '", exist[instance], "', but expected is '", expected, "'")
+		}
+	}
+}
+
+func TestMonitoring_Ports(t *testing.T) {
+	var (
+		lock  sync.Mutex
+		exist = map[int]string{}
+	)
+
+	monitoring := NewMonitoring(&testParameter{
+		command:       "sh",
+		args:          []string{"-c", "echo {{port}} $" + EnvPort + " $TEST_ADDR; sleep 0.2"},
+		runningMode:   RunOnce,
+		parallelCount: 3,
+		env:           []string{"TEST_ADDR=:{{port}}"},
+		portRange:     [2]int{32000, 64000},
+		stdoutSink: OutputSinkFunc(func(instance int, line string) {
+			lock.Lock()
+			exist[instance] = line
+			lock.Unlock()
+		}),
+	})
+
+	monitoring.Start(context.Background())
+	ports := monitoring.Ports()
+	monitoring.Wait()
+
+	if err := monitoring.HasError(); err != nil {
+		t.Error("failed to run command monitoring with", err)
+	}
+
+	lock.Lock()
+	defer lock.Unlock()
+
+	unique := map[int]bool{}
+	for instance, port := range ports {
+		unique[port] = true
+
+		if expected := fmt.Sprintf("%d %d :%d\n", port, port, port); exist[instance] != expected {
+			t.Error("failed to pass a port of instance #", instance, ". Got '", exist[instance], "', but expected is '", expected, "'")
+		}
+	}
+
+	if len(unique) != 3 {
+		t.Error("failed to assign unique ports. Got", ports)
+	}
+}
+
+func TestMonitoring_InvalidPortRange(t *testing.T) {
+	monitoring := NewMonitoring(&testParameter{
+		command:       "sh",
+		args:          []string{"-c", "echo started; sleep 10"},
+		runningMode:   RunOnce,
+		parallelCount: 1,
+		portRange:     [2]int{8080, 8080},
+	})
+
+	monitoring.Start(context.Background())
+	monitoring.Wait()
+
+	if err := monitoring.HasError(); err == nil {
+		t.Error("failed to catch an error of an invalid range of ports")
+	}
+}
@@ -4,9 +4,9 @@ package monitoring
 
 import "strconv"
 
-const _monitoringCommand_name = "cmdStartcmdKillcmdStop"
+const _monitoringCommand_name = "cmdStartcmdKillcmdStopcmdStartInstancecmdStopInstancecmdRestartInstancecmdRollingRestartcmdScale"
 
-var _monitoringCommand_index = [...]uint8{0, 8, 15, 22}
+var _monitoringCommand_index = [...]uint8{0, 8, 15, 22, 38, 53, 71, 88, 96}
 
 func (i monitoringCommand) String() string {
 	i -= 4
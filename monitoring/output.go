@@ -0,0 +1,111 @@
+package monitoring
+
+import (
+	"io"
+	"sync"
+)
+
+// OutputSink consumes lines of an output stream of every instance; a line ends with "\n".
+// It is called from a goroutine per stream and instance, so it should be safe for concurrent use.
+type OutputSink interface {
+	WriteLine(instance int, line string)
+}
+
+// OutputParameter is an optional extension of MonitoringParameter.
+// A nil sink discards an output, streams are drained anyway to don't block a command.
+// Sinks are created once per Monitoring and shared by every instance and run.
+type OutputParameter interface {
+	StdOutSink() OutputSink
+	StdErrSink() OutputSink
+}
+
+// OutputSinkFunc is an adapter to use a function as an OutputSink.
+type OutputSinkFunc func(instance int, line string)
+
+func (o OutputSinkFunc) WriteLine(instance int, line string) {
+	o(instance, line)
+}
+
+type multiSink []OutputSink
+
+// MultiSink duplicates lines into every sink.
+func MultiSink(sinks ...OutputSink) OutputSink {
+	return multiSink(sinks)
+}
+
+func (o multiSink) WriteLine(instance int, line string) {
+	for _, sink := range o {
+		sink.WriteLine(instance, line)
+	}
+}
+
+type discardSink struct{}
+
+func (discardSink) WriteLine(int, string) {}
+
+func DiscardSink() OutputSink {
+	return discardSink{}
+}
+
+type writerSink struct {
+	sync.Mutex
+
+	writer io.Writer
+}
+
+// WriterSink writes lines of every instance into the writer without interleaving them.
+func WriterSink(writer io.Writer) OutputSink {
+	return &writerSink{
+		writer: writer,
+	}
+}
+
+func (o *writerSink) WriteLine(_ int, line string) {
+	o.Lock()
+	io.WriteString(o.writer, line)
+	o.Unlock()
+}
+
+func outputSinks(parameter MonitoringParameter) (stdout OutputSink, stderr OutputSink, closer io.Closer) {
+	var (
+		stdoutSinks []OutputSink
+		stderrSinks []OutputSink
+	)
+
+	if output, ok := parameter.(OutputParameter); ok {
+		if sink := output.StdOutSink(); sink != nil {
+			stdoutSinks = append(stdoutSinks, sink)
+		}
+
+		if sink := output.StdErrSink(); sink != nil {
+			stderrSinks = append(stderrSinks, sink)
+		}
+	}
+
+	if logFile, ok := parameter.(LogFileParameter); ok && logFile.LogFile() != nil {
+		files := LogFiles(*logFile.LogFile())
+
+		stdoutSinks = append(stdoutSinks, files.Sink("stdout"))
+		stderrSinks = append(stderrSinks, files.Sink("stderr"))
+		closer = files
+	}
+
+	return joinSinks(stdoutSinks), joinSinks(stderrSinks), closer
+}
+
+func joinSinks(sinks []OutputSink) OutputSink {
+	switch len(sinks) {
+	case 0:
+		return DiscardSink()
+	case 1:
+		return sinks[0]
+	default:
+		return MultiSink(sinks...)
+	}
+}
+
+func drainStream(stream <-chan string, instance int, sink OutputSink) {
+	for line := range stream {
+		sink.WriteLine(instance, line)
+	}
+}
@@ -0,0 +1,63 @@
+package monitoring
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestWriterSink(t *testing.T) {
+	buffer := bytes.NewBufferString("")
+	sink := WriterSink(buffer)
+
+	sink.WriteLine(0, "line 1\n")
+	sink.WriteLine(1, "line 2\n")
+
+	if exist, expected := buffer.String(), "line 1\nline 2\n"; strings.Compare(exist, expected) != 0 {
+		t.Error("failed to write lines. Got '", exist, "', but expected is '", expected, "'")
+	}
+}
+
+func TestOutputSinks(t *testing.T) {
+	var exist []string
+
+	stdout, stderr, closer := outputSinks(&testParameter{
+		stdoutSink: OutputSinkFunc(func(instance int, line string) {
+			exist = append(exist, line)
+		}),
+	})
+
+	if _, ok := stderr.(discardSink); !ok {
+		t.Error("failed to use discard sink by default. Got", stderr)
+	}
+
+	if closer != nil {
+		t.Error("failed to skip log files. Got", closer)
+	}
+
+	drainStream(stringStream("line 1\n", "line 2\n"), 0, stdout)
+
+	if len(exist) != 2 || exist[1] != "line 2\n" {
+		t.Error("failed to drain a stream into a sink. Got", exist)
+	}
+}
+
+func TestMultiSink(t *testing.T) {
+	first, second := bytes.NewBufferString(""), bytes.NewBufferString("")
+
+	MultiSink(WriterSink(first), WriterSink(second)).WriteLine(0, "line\n")
+
+	if first.String() != "line\n" || second.String() != "line\n" {
+		t.Error("failed to duplicate a line. Got '", first.String(), "' and '", second.String(), "'")
+	}
+}
+
+func stringStream(lines ...string) <-chan string {
+	stream := make(chan string, len(lines))
+	for _, line := range lines {
+		stream <- line
+	}
+	close(stream)
+
+	return stream
+}
@@ -0,0 +1,115 @@
+package monitoring
+
+import (
+	"math/rand"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/pkg/errors"
+
+	"github.com/7phs/tools/common"
+)
+
+const (
+	// PortPlaceholder is replaced by a port of an instance in arguments and the environment.
+	PortPlaceholder = "{{port}}"
+
+	allocatePortCount = 10
+	maxPort           = 65535
+)
+
+// PortParameter is an optional extension of MonitoringParameter to assign a unique free port
+// from the range to every instance. A port is checked on every run and re-allocated if it is taken.
+// The range is in [1, 65535] and holds two ports at least, a zero range disables allocation.
+// A run fails to start when every port of the range is taken.
+type PortParameter interface {
+	PortRange() (min, max int)
+}
+
+type portAllocator struct {
+	sync.Mutex
+
+	min   int
+	max   int
+	ports map[int]int
+}
+
+func newPortAllocator(parameter MonitoringParameter) (*portAllocator, error) {
+	ports, ok := parameter.(PortParameter)
+	if !ok {
+		return nil, nil
+	}
+
+	min, max := ports.PortRange()
+	if min == 0 && max == 0 {
+		return nil, nil
+	}
+
+	if min < 1 || max > maxPort || min >= max {
+		return nil, errors.Errorf("invalid range of ports [%d, %d] - two ports in [1, %d] are required at least", min, max, maxPort)
+	}
+
+	return &portAllocator{
+		min:   min,
+		max:   max,
+		ports: make(map[int]int),
+	}, nil
+}
+
+// allocate keeps a port of the instance while it is free, otherwise it picks a free port of the range
+// which isn't assigned to another instance. Random ports are tried first, then the whole range.
+func (o *portAllocator) allocate(instance int) (int, error) {
+	o.Lock()
+	defer o.Unlock()
+
+	if port, ok := o.ports[instance]; ok && o.isFree(instance, port) {
+		return port, nil
+	}
+
+	for i := 0; i < allocatePortCount; i++ {
+		if port := o.min + rand.Intn(o.max-o.min+1); o.isFree(instance, port) {
+			o.ports[instance] = port
+			return port, nil
+		}
+	}
+
+	for port := o.min; port <= o.max; port++ {
+		if o.isFree(instance, port) {
+			o.ports[instance] = port
+			return port, nil
+		}
+	}
+
+	return 0, errors.Errorf("failed to allocate a port for instance #%d - all ports of [%d, %d] are taken", instance, o.min, o.max)
+}
+
+// release frees a port of a removed instance.
+func (o *portAllocator) release(instance int) {
+	o.Lock()
+	defer o.Unlock()
+
+	delete(o.ports, instance)
+}
+
+func (o *portAllocator) isAssigned(instance, port int) bool {
+	for other, assigned := range o.ports {
+		if other != instance && assigned == port {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (o *portAllocator) isFree(instance, port int) bool {
+	return !o.isAssigned(instance, port) && common.IsPortAvailable(port)
+}
+
+func expandPort(values []string, port int) []string {
+	for i, value := range values {
+		values[i] = strings.Replace(value, PortPlaceholder, strconv.Itoa(port), -1)
+	}
+
+	return values
+}
@@ -0,0 +1,116 @@
+package monitoring
+
+import (
+	"fmt"
+	"net"
+	"reflect"
+	"testing"
+)
+
+func TestNewPortAllocator(t *testing.T) {
+	if ports, err := newPortAllocator(&testParameter{}); ports != nil || err != nil {
+		t.Error("failed to skip allocation without a range. Got", ports, err)
+	}
+
+	if ports, err := newPortAllocator(&testParameter{portRange: [2]int{32000, 33000}}); ports == nil || err != nil {
+		t.Error("failed to create an allocator with a range. Got", err)
+	}
+
+	for _, portRange := range [][2]int{{8080, 8080}, {0, 8080}, {-1, 8080}, {8080, 8000}, {8080, 70000}} {
+		if _, err := newPortAllocator(&testParameter{portRange: portRange}); err == nil {
+			t.Error("failed to catch an error of an invalid range", portRange)
+		}
+	}
+}
+
+func TestPortAllocator_Allocate(t *testing.T) {
+	min, max := 32000, 64000
+	ports, _ := newPortAllocator(&testParameter{portRange: [2]int{min, max}})
+
+	exist := map[int]bool{}
+	for instance := 0; instance < 10; instance++ {
+		port, err := ports.allocate(instance)
+		if err != nil || port < min || port > max {
+			t.Error("failed to allocate a port in range. Got", port, err)
+		}
+
+		exist[port] = true
+	}
+
+	if len(exist) != 10 {
+		t.Error("failed to allocate unique ports. Got", exist)
+	}
+
+	port, _ := ports.allocate(0)
+	if exist, _ := ports.allocate(0); exist != port {
+		t.Error("failed to keep a free port of an instance. Got", exist, ", but expected is", port)
+	}
+
+	ln, err := net.Listen("tcp", fmt.Sprintf(":%d", port))
+	if err != nil {
+		t.Error("failed to listen a port", port, "with", err)
+		return
+	}
+	defer ln.Close()
+
+	if exist, _ := ports.allocate(0); exist == port {
+		t.Error("failed to re-allocate a taken port", port)
+	}
+}
+
+func TestPortAllocator_AllocateTaken(t *testing.T) {
+	var (
+		min, max = 64500, 64502
+		taken    []net.Listener
+	)
+
+	ports, _ := newPortAllocator(&testParameter{portRange: [2]int{min, max}})
+
+	for port := min; port <= max; port++ {
+		ln, err := net.Listen("tcp", fmt.Sprintf(":%d", port))
+		if err != nil {
+			t.Skip("failed to listen a port", port, "with", err)
+		}
+		defer ln.Close()
+
+		taken = append(taken, ln)
+
+		exist, err := ports.allocate(0)
+		if port < max && (err != nil || exist <= port || exist > max) {
+			t.Error("failed to allocate a free port of the range. Got", exist, err)
+		}
+	}
+
+	ports.release(0)
+
+	if exist, err := ports.allocate(1); err == nil {
+		t.Error("failed to catch an error of a taken range. Got", exist)
+	}
+}
+
+func TestPortAllocator_AllocateUnique(t *testing.T) {
+	min, max := 64500, 64501
+	ports, _ := newPortAllocator(&testParameter{portRange: [2]int{min, max}})
+
+	first, err := ports.allocate(0)
+	if err != nil {
+		t.Skip("failed to allocate a port with", err)
+	}
+
+	if second, err := ports.allocate(1); err != nil || second == first || second < min || second > max {
+		t.Error("failed to allocate a unique port of the range. Got", second, err, ", but", first, "is assigned")
+	}
+
+	if exist, err := ports.allocate(2); err == nil {
+		t.Error("failed to catch an error of an assigned range. Got", exist)
+	}
+}
+
+func TestExpandPort(t *testing.T) {
+	exist := expandPort([]string{"--port={{port}}", "-v", "ADDR=:{{port}}"}, 8080)
+	expected := []string{"--port=8080", "-v", "ADDR=:8080"}
+
+	if !reflect.DeepEqual(exist, expected) {
+		t.Error("failed to expand a port. Got", exist, ", but expected is", expected)
+	}
+}
@@ -0,0 +1,107 @@
+//go:build !windows
+// +build !windows
+
+package monitoring
+
+import (
+	"os"
+	"os/exec"
+	"sync"
+	"syscall"
+
+	"github.com/pkg/errors"
+)
+
+func setProcessGroup(cmd *exec.Cmd) {
+	if cmd.SysProcAttr == nil {
+		cmd.SysProcAttr = &syscall.SysProcAttr{}
+	}
+
+	cmd.SysProcAttr.Setpgid = true
+}
+
+const credentialSupported = true
+
+// signalNames are signals to stop a command by names without the SIG prefix.
+var signalNames = map[string]os.Signal{
+	"HUP":  syscall.SIGHUP,
+	"INT":  syscall.SIGINT,
+	"QUIT": syscall.SIGQUIT,
+	"KILL": syscall.SIGKILL,
+	"USR1": syscall.SIGUSR1,
+	"USR2": syscall.SIGUSR2,
+	"TERM": syscall.SIGTERM,
+}
+
+// umaskLock keeps other commands from starting and files of the package from being created
+// while an umask is changed for a command.
+var umaskLock sync.RWMutex
+
+// keepUmask keeps an umask of a starting command from files created by fn.
+func keepUmask(fn func() error) error {
+	umaskLock.RLock()
+	defer umaskLock.RUnlock()
+
+	return fn()
+}
+
+// setProcessAttrs sets attributes applied by SysProcAttr.
+func setProcessAttrs(cmd *exec.Cmd, attrs *ProcessAttrs) {
+	if cmd.SysProcAttr == nil {
+		cmd.SysProcAttr = &syscall.SysProcAttr{}
+	}
+
+	if attrs.Credential != nil {
+		cmd.SysProcAttr.Credential = &syscall.Credential{
+			Uid:    attrs.Credential.Uid,
+			Gid:    attrs.Credential.Gid,
+			Groups: attrs.Credential.Groups,
+		}
+	}
+
+	// a session leader leads a new process group already, setpgid fails for it
+	if attrs.Setsid {
+		cmd.SysProcAttr.Setsid = true
+		cmd.SysProcAttr.Setpgid = false
+	}
+}
+
+// startWithAttrs starts a command with an umask of attributes, it is inherited from the current process.
+func startWithAttrs(cmd *exec.Cmd, attrs *ProcessAttrs) error {
+	if attrs == nil || attrs.Umask == nil {
+		umaskLock.RLock()
+		defer umaskLock.RUnlock()
+
+		return startWithRlimits(cmd, attrs)
+	}
+
+	umaskLock.Lock()
+	defer umaskLock.Unlock()
+
+	defer syscall.Umask(syscall.Umask(int(*attrs.Umask)))
+
+	return startWithRlimits(cmd, attrs)
+}
+
+// signalGroup sends a signal to every process of a group which leader is the process.
+func signalGroup(process *os.Process, sig os.Signal) error {
+	sysSig, ok := sig.(syscall.Signal)
+	if !ok {
+		return errors.Errorf("unsupported signal %v", sig)
+	}
+
+	err := syscall.Kill(-process.Pid, sysSig)
+	if err == syscall.ESRCH {
+		return nil
+	}
+
+	return err
+}
+
+func exitStatus(state *os.ProcessState) (int, os.Signal) {
+	if status, ok := state.Sys().(syscall.WaitStatus); ok && status.Signaled() {
+		return -1, status.Signal()
+	}
+
+	return state.ExitCode(), nil
+}
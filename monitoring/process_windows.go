@@ -0,0 +1,42 @@
+//go:build windows
+// +build windows
+
+package monitoring
+
+import (
+	"os"
+	"os/exec"
+	"syscall"
+)
+
+func setProcessGroup(cmd *exec.Cmd) {
+}
+
+const credentialSupported = false
+
+// signalNames are signals to stop a command by names without the SIG prefix.
+var signalNames = map[string]os.Signal{
+	"INT":  os.Interrupt,
+	"KILL": os.Kill,
+	"TERM": syscall.SIGTERM,
+}
+
+func setProcessAttrs(*exec.Cmd, *ProcessAttrs) {
+}
+
+func startWithAttrs(cmd *exec.Cmd, _ *ProcessAttrs) error {
+	return cmd.Start()
+}
+
+func keepUmask(fn func() error) error {
+	return fn()
+}
+
+// signalGroup falls back to signal the process only, there are no process groups.
+func signalGroup(process *os.Process, sig os.Signal) error {
+	return process.Signal(sig)
+}
+
+func exitStatus(state *os.ProcessState) (int, os.Signal) {
+	return state.ExitCode(), nil
+}
@@ -0,0 +1,110 @@
+package monitoring
+
+import (
+	"os"
+
+	"github.com/pkg/errors"
+
+	"github.com/7phs/tools/common"
+)
+
+// Rlimit is a soft and a hard limit of a resource, see setrlimit(2).
+type Rlimit struct {
+	Soft uint64
+	Hard uint64
+}
+
+// Credential is a user and groups to run a command as, the current process must be privileged.
+// Supplementary groups are dropped without Groups.
+type Credential struct {
+	Uid    uint32
+	Gid    uint32
+	Groups []uint32
+}
+
+// ProcessAttrs describes attributes of a process of a command, nil fields keep ones of the current process.
+//
+// Credential and Setsid are set by SysProcAttr. Umask is set for the current process while a command starts,
+// files of the package wait for it, but other files created by the program meanwhile get the umask as well.
+// Limits are set by prlimit(2) while a command is stopped by ptrace(2) right after exec, so it runs with them
+// from the start. They are supported on Linux only. A command with limits fails to start where ptrace(2)
+// is restricted, like by Yama ptrace_scope 3 or a seccomp profile. Limits of a command run as another user
+// or raising hard limits require CAP_SYS_RESOURCE. Setsid starts a new process group as well.
+type ProcessAttrs struct {
+	NoFile *Rlimit // RLIMIT_NOFILE
+	Core   *Rlimit // RLIMIT_CORE, bytes
+	AS     *Rlimit // RLIMIT_AS, bytes
+
+	Credential *Credential
+	Umask      *os.FileMode
+	Setsid     bool
+}
+
+// ProcessAttrsParameter is an optional extension of Parameter.
+type ProcessAttrsParameter interface {
+	ProcessAttrs() *ProcessAttrs
+}
+
+// Resources of limits, they are mapped to values of a platform.
+const (
+	rlimitNoFile = iota
+	rlimitCore
+	rlimitAS
+)
+
+type namedRlimit struct {
+	Rlimit
+
+	name     string
+	resource int
+}
+
+// limits returns set limits in order of fields.
+func (o *ProcessAttrs) limits() []namedRlimit {
+	var limits []namedRlimit
+
+	add := func(name string, resource int, limit *Rlimit) {
+		if limit != nil {
+			limits = append(limits, namedRlimit{Rlimit: *limit, name: name, resource: resource})
+		}
+	}
+
+	add("NOFILE", rlimitNoFile, o.NoFile)
+	add("CORE", rlimitCore, o.Core)
+	add("AS", rlimitAS, o.AS)
+
+	return limits
+}
+
+func (o *ProcessAttrs) hasLimits() bool {
+	return len(o.limits()) > 0
+}
+
+// check reports every attribute which can't be applied.
+func (o *ProcessAttrs) check() error {
+	if o == nil {
+		return nil
+	}
+
+	var errs []error
+
+	for _, limit := range o.limits() {
+		if limit.Soft > limit.Hard {
+			errs = append(errs, errors.Errorf("a soft limit %d of %s exceeds the hard one %d", limit.Soft, limit.name, limit.Hard))
+		}
+	}
+
+	if o.hasLimits() && !rlimitSupported {
+		errs = append(errs, errors.New("limits of resources are supported on linux only"))
+	}
+
+	if o.Umask != nil && *o.Umask&^os.ModePerm != 0 {
+		errs = append(errs, errors.Errorf("invalid umask %v", *o.Umask))
+	}
+
+	if (o.Credential != nil || o.Umask != nil || o.Setsid) && !credentialSupported {
+		errs = append(errs, errors.New("a credential, an umask and a session are supported on unix only"))
+	}
+
+	return common.SeveralErrors("invalid attributes of a process", errs...)
+}
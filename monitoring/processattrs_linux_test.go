@@ -0,0 +1,188 @@
+//go:build linux
+// +build linux
+
+package monitoring
+
+import (
+	"io/ioutil"
+	"os"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func procLines(t *testing.T, pid int, name string) []string {
+	content, err := ioutil.ReadFile("/proc/" + strconv.Itoa(pid) + "/" + name)
+	if err != nil {
+		t.Fatal("failed to read", name, "with", err)
+	}
+
+	return strings.Split(string(content), "\n")
+}
+
+func procField(lines []string, prefix string) []string {
+	for _, line := range lines {
+		if strings.HasPrefix(line, prefix) {
+			return strings.Fields(strings.TrimPrefix(line, prefix))
+		}
+	}
+
+	return nil
+}
+
+func TestCoreCmd_ProcessAttrs(t *testing.T) {
+	umask := os.FileMode(0027)
+	attrs := &ProcessAttrs{
+		NoFile: &Rlimit{Soft: 100, Hard: 200},
+		Core:   &Rlimit{Soft: 0, Hard: 0},
+		AS:     &Rlimit{Soft: 1 << 30, Hard: 1 << 31},
+		Umask:  &umask,
+		Setsid: true,
+	}
+
+	cmd, err := NewCoreCmd(&testParameter{
+		command:      "sleep",
+		args:         []string{"10"},
+		processGroup: true,
+		attrs:        attrs,
+	})
+	if err != nil {
+		t.Fatal("failed to create command with", err)
+	}
+
+	if err := cmd.Start(); err != nil {
+		t.Fatal("failed to start command with", err)
+	}
+	defer cmd.Kill()
+
+	pid := cmd.Pid()
+	limits := procLines(t, pid, "limits")
+
+	testSuites := []struct {
+		name     string
+		expected []string
+	}{
+		{name: "Max open files", expected: []string{"100", "200"}},
+		{name: "Max core file size", expected: []string{"0", "0"}},
+		{name: "Max address space", expected: []string{strconv.FormatInt(1<<30, 10), strconv.FormatInt(1<<31, 10)}},
+	}
+
+	for _, test := range testSuites {
+		if exist := procField(limits, test.name); len(exist) < 2 || exist[0] != test.expected[0] || exist[1] != test.expected[1] {
+			t.Error("failed to set a limit", test.name, ". Got", exist, ", but expected is", test.expected)
+		}
+	}
+
+	status := procLines(t, pid, "status")
+
+	if exist := procField(status, "Umask:"); len(exist) != 1 || exist[0] != "0027" {
+		t.Error("failed to set umask. Got", exist, ", but expected is", "0027")
+	}
+
+	// a session and a process group are led by the command
+	stat := strings.Fields(procLines(t, pid, "stat")[0])
+	if stat[4] != strconv.Itoa(pid) || stat[5] != strconv.Itoa(pid) {
+		t.Error("failed to start a new session. Got group", stat[4], "and session", stat[5], ", but expected is", pid)
+	}
+}
+
+func TestCoreCmd_RlimitsAtStart(t *testing.T) {
+	cmd, err := NewCoreCmd(&testParameter{
+		command: "sh",
+		args:    []string{"-c", "echo $(ulimit -Sn) $(ulimit -Hn); sleep 10"},
+		attrs:   &ProcessAttrs{NoFile: &Rlimit{Soft: 100, Hard: 200}},
+	})
+	if err != nil {
+		t.Fatal("failed to create command with", err)
+	}
+
+	if err := cmd.Start(); err != nil {
+		t.Fatal("failed to start command with", err)
+	}
+	defer cmd.Kill()
+
+	// the command has limits before its first instruction
+	select {
+	case line := <-cmd.StdOut():
+		if expected := "100 200\n"; line != expected {
+			t.Error("failed to start a command with limits. Got", line, ", but expected is", expected)
+		}
+
+	case <-time.After(5 * time.Second):
+		t.Error("failed to wait for an output of a command")
+	}
+}
+
+func TestKeepUmask(t *testing.T) {
+	umaskLock.Lock()
+
+	done := make(chan interface{})
+	go keepUmask(func() error {
+		close(done)
+		return nil
+	})
+
+	select {
+	case <-done:
+		t.Error("failed to wait for an umask of a starting command")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	umaskLock.Unlock()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Error("failed to create files after a start of a command")
+	}
+}
+
+func TestCoreCmd_Credential(t *testing.T) {
+	if os.Getuid() != 0 {
+		t.Skip("a privileged process is required to change a user")
+	}
+
+	cmd, err := NewCoreCmd(&testParameter{
+		command: "sleep",
+		args:    []string{"10"},
+		attrs:   &ProcessAttrs{Credential: &Credential{Uid: 65534, Gid: 65534}},
+	})
+	if err != nil {
+		t.Fatal("failed to create command with", err)
+	}
+
+	if err := cmd.Start(); err != nil {
+		t.Fatal("failed to start command with", err)
+	}
+	defer cmd.Kill()
+
+	status := procLines(t, cmd.Pid(), "status")
+
+	for _, name := range []string{"Uid:", "Gid:"} {
+		if exist := procField(status, name); len(exist) == 0 || exist[0] != "65534" {
+			t.Error("failed to set", name, "Got", exist, ", but expected is", 65534)
+		}
+	}
+
+	if exist := procField(status, "Groups:"); len(exist) != 0 {
+		t.Error("failed to drop supplementary groups. Got", exist)
+	}
+}
+
+func TestCoreCmd_ProcessAttrsFailure(t *testing.T) {
+	// the kernel caps NOFILE by fs.nr_open even for a privileged process
+	cmd, err := NewCoreCmd(&testParameter{
+		command: "sleep",
+		args:    []string{"10"},
+		attrs:   &ProcessAttrs{NoFile: &Rlimit{Soft: 1 << 40, Hard: 1 << 40}},
+	})
+	if err != nil {
+		t.Fatal("failed to create command with", err)
+	}
+
+	if err := cmd.Start(); err == nil || !strings.Contains(err.Error(), "NOFILE") {
+		cmd.Kill()
+		t.Error("failed to catch an error of a limit. Got", err)
+	}
+}
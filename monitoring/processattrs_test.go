@@ -0,0 +1,58 @@
+package monitoring
+
+import (
+	"context"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestProcessAttrs_Check(t *testing.T) {
+	var nilAttrs *ProcessAttrs
+	if err := nilAttrs.check(); err != nil {
+		t.Error("failed to skip nil attributes. Got", err)
+	}
+
+	umask := os.FileMode(0022)
+	if err := (&ProcessAttrs{NoFile: &Rlimit{Soft: 10, Hard: 10}, Umask: &umask}).check(); err != nil && rlimitSupported {
+		t.Error("failed to check valid attributes. Got", err)
+	}
+
+	invalidUmask := os.ModeDir | 0022
+
+	err := (&ProcessAttrs{
+		NoFile: &Rlimit{Soft: 20, Hard: 10},
+		Core:   &Rlimit{Soft: 0, Hard: 0},
+		AS:     &Rlimit{Soft: 2, Hard: 1},
+		Umask:  &invalidUmask,
+	}).check()
+	if err == nil {
+		t.Fatal("failed to catch invalid attributes")
+	}
+
+	for _, expected := range []string{"NOFILE", "AS", "umask"} {
+		if !strings.Contains(err.Error(), expected) {
+			t.Error("failed to report an invalid attribute", expected, ". Got", err)
+		}
+	}
+
+	if strings.Contains(err.Error(), "CORE") {
+		t.Error("failed to skip a valid limit. Got", err)
+	}
+}
+
+func TestMonitoring_InvalidProcessAttrs(t *testing.T) {
+	monitoring := NewMonitoring(&testParameter{
+		command:     "sh",
+		args:        []string{"-c", "echo started; sleep 10"},
+		runningMode: RunOnce,
+		attrs:       &ProcessAttrs{NoFile: &Rlimit{Soft: 20, Hard: 10}},
+	})
+
+	monitoring.Start(context.Background())
+	monitoring.Wait()
+
+	if err := monitoring.HasError(); err == nil || !strings.Contains(err.Error(), "NOFILE") {
+		t.Error("failed to catch invalid attributes. Got", err)
+	}
+}
@@ -0,0 +1,151 @@
+//go:build linux
+// +build linux
+
+package monitoring
+
+import (
+	"context"
+	"io/ioutil"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+)
+
+// processGroupSurvivors lists alive (not zombie) processes of the group.
+func processGroupSurvivors(pgid int) (pids []int) {
+	stats, _ := filepath.Glob("/proc/[0-9]*/stat")
+
+	for _, stat := range stats {
+		content, err := ioutil.ReadFile(stat)
+		if err != nil {
+			continue
+		}
+
+		// pid (comm) state ppid pgrp ...
+		fields := strings.Fields(string(content[strings.LastIndex(string(content), ")")+1:]))
+		if len(fields) < 3 || fields[0] == "Z" || fields[2] != strconv.Itoa(pgid) {
+			continue
+		}
+
+		pid, _ := strconv.Atoi(filepath.Base(filepath.Dir(stat)))
+		pids = append(pids, pid)
+	}
+
+	return
+}
+
+func TestCoreCmd_KillProcessGroup(t *testing.T) {
+	cmd, err := NewCoreCmd(&testParameter{
+		command:      "sh",
+		args:         []string{"-c", "sleep 100 & wait"},
+		processGroup: true,
+	})
+	if cmd == nil || err != nil {
+		t.Error("failed to create command with", err)
+		return
+	}
+
+	if err := cmd.Start(); err != nil {
+		t.Error("failed to start command with", err)
+		return
+	}
+
+	pgid := cmd.cmd.Process.Pid
+
+	time.Sleep(100 * time.Millisecond)
+
+	if exist := processGroupSurvivors(pgid); len(exist) < 2 {
+		t.Error("failed to start a child in the process group. Got processes", exist)
+	}
+
+	if err := cmd.Kill(); err != nil {
+		t.Error("failed to kill the command with", err)
+	}
+	<-cmd.Wait()
+
+	time.Sleep(100 * time.Millisecond)
+
+	if exist := processGroupSurvivors(pgid); len(exist) > 0 {
+		t.Error("failed to kill the whole process group. Got survivors", exist)
+	}
+}
+
+func TestMonitoring_StopProcessGroup(t *testing.T) {
+	monitoring := NewMonitoring(&testParameter{
+		command:       "sh",
+		args:          []string{"-c", "sleep 100 & echo started; wait"},
+		parallelCount: 3,
+		stopSignal:    syscall.SIGTERM,
+		stopTimeout:   time.Second,
+		processGroup:  true,
+	})
+
+	monitoring.Start(context.Background())
+
+	if err := monitoring.HasError(); err != nil {
+		t.Error("failed to start command monitoring with", err)
+	}
+
+	var groups []int
+	for _, cmd := range monitoring.cmd {
+		groups = append(groups, cmd.cmd.Process.Pid)
+	}
+
+	monitoring.Stop(context.Background())
+	monitoring.Wait()
+
+	if err := monitoring.HasError(); err != nil {
+		t.Error("failed to stop command monitoring with", err)
+	}
+
+	time.Sleep(100 * time.Millisecond)
+
+	for _, pgid := range groups {
+		if exist := processGroupSurvivors(pgid); len(exist) > 0 {
+			t.Error("failed to stop the whole process group", pgid, ". Got survivors", exist)
+		}
+	}
+}
+
+func TestMonitoring_ProcessGroupRestart(t *testing.T) {
+	monitoring := NewMonitoring(&testParameter{
+		command:       "sh",
+		args:          []string{"-c", "sleep 77.5 & echo started; sleep 0.2; exit 1"},
+		runningMode:   RepeatInfinity,
+		parallelCount: 1,
+		processGroup:  true,
+	})
+
+	events, unsubscribe := monitoring.Subscribe(100)
+
+	monitoring.Start(context.Background())
+
+	// a leader exits by itself and leaves a child
+	var groups []int
+	for len(groups) < 3 {
+		select {
+		case event := <-events:
+			if event.Type == EventStarted {
+				groups = append(groups, event.Pid)
+			}
+
+		case <-time.After(5 * time.Second):
+			t.Fatal("failed to wait for restarts. Got groups", groups)
+		}
+	}
+	unsubscribe()
+
+	monitoring.Stop(context.Background())
+	monitoring.Wait()
+
+	time.Sleep(100 * time.Millisecond)
+
+	for _, pgid := range groups {
+		if exist := processGroupSurvivors(pgid); len(exist) > 0 {
+			t.Error("failed to kill children of an exited leader", pgid, ". Got survivors", exist)
+		}
+	}
+}
@@ -0,0 +1,146 @@
+package monitoring
+
+import (
+	"context"
+	"regexp"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/7phs/tools/common"
+)
+
+const (
+	defaultReadinessInterval = 100 * time.Millisecond
+)
+
+// ReadinessConfig describes conditions of a ready instance used instead of CheckStartLine.
+// Every set condition should be satisfied till the deadline of the start context:
+// Check is polled till it succeeds (HTTPCheck, TCPCheck, FileCheck, ExecCheck),
+// StdOut and StdErr wait for a matched line of a stream.
+// A stderr output doesn't fail a start regardless of StdErrIsOk.
+type ReadinessConfig struct {
+	Check    HealthCheck
+	Interval time.Duration
+	StdOut   *regexp.Regexp
+	StdErr   *regexp.Regexp
+}
+
+// ReadinessParameter is an optional extension of MonitoringParameter.
+type ReadinessParameter interface {
+	Readiness() *ReadinessConfig
+}
+
+// matchSink passes lines to the sink and closes the channel on the first matched line.
+func matchSink(sink OutputSink, re *regexp.Regexp) (OutputSink, <-chan struct{}) {
+	if re == nil {
+		return sink, nil
+	}
+
+	matched := make(chan struct{})
+
+	return OutputSinkFunc(func(instance int, line string) {
+		sink.WriteLine(instance, line)
+
+		if matched != nil && re.MatchString(line) {
+			close(matched)
+			matched = nil
+		}
+	}), matched
+}
+
+func (o *commandState) waitReadiness(ctx context.Context) error {
+	if err := o.startProcess(); err != nil {
+		return err
+	}
+
+	var (
+		config   = o.readiness
+		pending  = 0
+		checked  chan error
+		timeout  = common.DeadLineTimer(ctx.Deadline())
+		stdout   OutputSink
+		stderr   OutputSink
+		outMatch <-chan struct{}
+		errMatch <-chan struct{}
+	)
+	defer timeout.Stop()
+
+	stdout, outMatch = matchSink(o.stdoutSink, config.StdOut)
+	stderr, errMatch = matchSink(o.stderrSink, config.StdErr)
+
+	go drainStream(o.StdOut(), o.index, stdout)
+	go drainStream(o.StdErr(), o.index, stderr)
+
+	if outMatch != nil {
+		pending++
+	}
+
+	if errMatch != nil {
+		pending++
+	}
+
+	if config.Check != nil {
+		pending++
+
+		// a command context doesn't support derived contexts, waiting is bounded below anyway
+		pollCtx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		checked = make(chan error, 1)
+		go o.pollReadiness(pollCtx, config, checked)
+	}
+
+	for pending > 0 {
+		select {
+		case <-outMatch:
+			outMatch = nil
+			pending--
+
+		case <-errMatch:
+			errMatch = nil
+			pending--
+
+		case <-checked:
+			checked = nil
+			pending--
+
+		case <-o.Wait():
+			return errors.New("command exited before it became ready")
+
+		case <-timeout.C:
+			return errors.New("timeout error")
+
+		case <-ctx.Done():
+			return errors.New("user cancel")
+		}
+	}
+
+	return nil
+}
+
+func (o *commandState) pollReadiness(ctx context.Context, config *ReadinessConfig, checked chan<- error) {
+	interval := config.Interval
+	if interval <= 0 {
+		interval = defaultReadinessInterval
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	instance := o.instance()
+
+	for {
+		if config.Check.Check(ctx, instance) == nil {
+			checked <- nil
+			return
+		}
+
+		select {
+		case <-ticker.C:
+
+		case <-ctx.Done():
+			return
+		}
+	}
+}
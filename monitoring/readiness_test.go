@@ -0,0 +1,39 @@
+package monitoring
+
+import (
+	"regexp"
+	"testing"
+)
+
+func TestMatchSink(t *testing.T) {
+	var exist []string
+
+	sink, matched := matchSink(OutputSinkFunc(func(instance int, line string) {
+		exist = append(exist, line)
+	}), regexp.MustCompile(`^ready`))
+
+	sink.WriteLine(0, "starting\n")
+
+	select {
+	case <-matched:
+		t.Error("failed to skip an unmatched line")
+	default:
+	}
+
+	sink.WriteLine(0, "ready\n")
+	sink.WriteLine(0, "ready again\n")
+
+	select {
+	case <-matched:
+	default:
+		t.Error("failed to match a line")
+	}
+
+	if len(exist) != 3 {
+		t.Error("failed to pass lines to a sink. Got", exist)
+	}
+
+	if _, matched := matchSink(DiscardSink(), nil); matched != nil {
+		t.Error("failed to skip matching without a regexp")
+	}
+}
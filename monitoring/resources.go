@@ -0,0 +1,198 @@
+package monitoring
+
+import (
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	defaultResourceInterval = time.Second
+	defaultCPUWindow        = time.Minute
+)
+
+// ResourceStats is a resource usage of a process of an instance, children aren't counted.
+type ResourceStats struct {
+	Instance int
+	Pid      int
+	CPUTime  time.Duration // user and system time of the run
+	RSS      int64         // resident memory in bytes
+	FDs      int           // open file descriptors
+	Threads  int
+	Time     time.Time
+}
+
+// ResourceParameter is an optional extension of MonitoringParameter to sample a resource usage
+// of every running instance with the interval. Zero interval disables sampling without limits.
+// Sampling is supported on Linux only, it reads /proc/<pid>.
+type ResourceParameter interface {
+	ResourceInterval() time.Duration
+}
+
+// ResourceLimits describes limits of every instance, zero fields aren't checked.
+// CPU usage is averaged over CPUWindow and checked once the window is sampled, so short spikes are ignored.
+type ResourceLimits struct {
+	MaxRSS        int64 // bytes
+	MaxCPUPercent float64
+	CPUWindow     time.Duration
+	MaxFDs        int
+}
+
+// ResourceLimitParameter is an optional extension of MonitoringParameter. An instance exceeding limits
+// is killed and restarted the same as an unhealthy one. Limits are checked on every sample
+// of ResourceParameter, every second by default.
+type ResourceLimitParameter interface {
+	ResourceLimits() *ResourceLimits
+}
+
+func (o ResourceLimits) withDefaults() ResourceLimits {
+	if o.CPUWindow <= 0 {
+		o.CPUWindow = defaultCPUWindow
+	}
+
+	return o
+}
+
+// check reports the first exceeded limit.
+func (o ResourceLimits) check(stats ResourceStats, cpu *cpuWindow) error {
+	if o.MaxRSS > 0 && stats.RSS > o.MaxRSS {
+		return errors.Errorf("RSS %d bytes exceeds the limit of %d bytes", stats.RSS, o.MaxRSS)
+	}
+
+	if o.MaxFDs > 0 && stats.FDs > o.MaxFDs {
+		return errors.Errorf("%d open file descriptors exceed the limit of %d", stats.FDs, o.MaxFDs)
+	}
+
+	if o.MaxCPUPercent > 0 {
+		if percent, ok := cpu.percent(stats, o.CPUWindow); ok && percent > o.MaxCPUPercent {
+			return errors.Errorf("CPU usage %.1f%% over %v exceeds the limit of %.1f%%", percent, o.CPUWindow, o.MaxCPUPercent)
+		}
+	}
+
+	return nil
+}
+
+// cpuWindow keeps samples of a run to average CPU usage over a window.
+type cpuWindow struct {
+	pid     int
+	samples []ResourceStats
+}
+
+// percent returns CPU usage since the oldest sample covering the window, false till the window is sampled.
+func (o *cpuWindow) percent(stats ResourceStats, window time.Duration) (float64, bool) {
+	// a new run starts a new window
+	if stats.Pid != o.pid {
+		o.pid, o.samples = stats.Pid, o.samples[:0]
+	}
+
+	o.samples = append(o.samples, stats)
+
+	for len(o.samples) > 1 && stats.Time.Sub(o.samples[1].Time) >= window {
+		o.samples = o.samples[1:]
+	}
+
+	first := o.samples[0]
+
+	elapsed := stats.Time.Sub(first.Time)
+	if elapsed < window {
+		return 0, false
+	}
+
+	return float64(stats.CPUTime-first.CPUTime) / float64(elapsed) * 100, true
+}
+
+func (o *commandState) setStats(stats ResourceStats) {
+	o.stats.Store(stats)
+}
+
+// Stats returns the last sampled resource usage, the zero value if nothing is sampled.
+func (o *commandState) Stats() ResourceStats {
+	stats, _ := o.stats.Load().(ResourceStats)
+
+	return stats
+}
+
+// Stats returns the last sampled resource usage of every parallel instance.
+func (o *Monitoring) Stats() []ResourceStats {
+	cmds := o.commands()
+	stats := make([]ResourceStats, len(cmds))
+
+	for i, cmd := range cmds {
+		if cmd != nil {
+			stats[i] = cmd.Stats()
+		}
+	}
+
+	return stats
+}
+
+// resourceSampling returns an interval of sampling, zero disables it, and limits if they are set.
+func (o *Monitoring) resourceSampling() (interval time.Duration, limits *ResourceLimits) {
+	if parameter, ok := o.MonitoringParameter.(ResourceParameter); ok {
+		interval = parameter.ResourceInterval()
+	}
+
+	if parameter, ok := o.MonitoringParameter.(ResourceLimitParameter); ok && parameter.ResourceLimits() != nil {
+		config := parameter.ResourceLimits().withDefaults()
+		limits = &config
+
+		if interval <= 0 {
+			interval = defaultResourceInterval
+		}
+	}
+
+	return
+}
+
+// resourceProcess samples a resource usage of an instance periodically and emits EventResources.
+// An instance exceeding limits is killed, so monitoringProcess restarts it the same as a crashed one.
+func (o *Monitoring) resourceProcess(cmd *commandState, interval time.Duration, limits *ResourceLimits, stop <-chan interface{}) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	var cpu cpuWindow
+
+	for {
+		select {
+		case <-ticker.C:
+
+		case <-stop:
+			return
+		}
+
+		// skip a finished run, it is restarting or stopped
+		status := cmd.status()
+		if status.Runs == 0 || status.Exited {
+			continue
+		}
+
+		stats, err := readResourceStats(status.Pid)
+		if err != nil {
+			// the process could exit while reading
+			continue
+		}
+
+		stats.Instance = cmd.index
+		cmd.setStats(stats)
+
+		cmd.events.emit(Event{
+			Type:     EventResources,
+			Instance: cmd.index,
+			Pid:      stats.Pid,
+			Time:     stats.Time,
+			Stats:    &stats,
+		})
+
+		if limits == nil {
+			continue
+		}
+
+		if err := limits.check(stats, &cpu); err != nil {
+			cmd.controlRun(status.Runs, func() {
+				cmd.markUnhealthy()
+				cmd.emit(EventLimitExceeded, err)
+				cmd.Kill()
+			})
+		}
+	}
+}
@@ -0,0 +1,70 @@
+//go:build linux
+// +build linux
+
+package monitoring
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	// clockTicks is USER_HZ of times in /proc, it is fixed for user space
+	clockTicks = 100
+
+	// indexes of fields of /proc/<pid>/stat after a command name
+	statUserTime = 11
+	statSysTime  = 12
+	statThreads  = 17
+	statRSS      = 21
+)
+
+func readResourceStats(pid int) (ResourceStats, error) {
+	stats := ResourceStats{
+		Pid:  pid,
+		Time: time.Now(),
+	}
+
+	dir := filepath.Join("/proc", strconv.Itoa(pid))
+
+	content, err := ioutil.ReadFile(filepath.Join(dir, "stat"))
+	if err != nil {
+		return stats, errors.Wrapf(err, "failed to read stat of %d", pid)
+	}
+
+	// a command name in parentheses could contain spaces and parentheses
+	end := strings.LastIndexByte(string(content), ')')
+	if end < 0 {
+		return stats, errors.Errorf("failed to parse stat of %d", pid)
+	}
+
+	fields := strings.Fields(string(content[end+1:]))
+	if len(fields) <= statRSS {
+		return stats, errors.Errorf("failed to parse stat of %d - %d fields only", pid, len(fields))
+	}
+
+	values := make(map[int]int64, 4)
+	for _, index := range []int{statUserTime, statSysTime, statThreads, statRSS} {
+		if values[index], err = strconv.ParseInt(fields[index], 10, 64); err != nil {
+			return stats, errors.Wrapf(err, "failed to parse stat of %d", pid)
+		}
+	}
+
+	stats.CPUTime = time.Duration(values[statUserTime]+values[statSysTime]) * time.Second / clockTicks
+	stats.Threads = int(values[statThreads])
+	stats.RSS = values[statRSS] * int64(os.Getpagesize())
+
+	fds, err := ioutil.ReadDir(filepath.Join(dir, "fd"))
+	if err != nil {
+		return stats, errors.Wrapf(err, "failed to read file descriptors of %d", pid)
+	}
+	stats.FDs = len(fds)
+
+	return stats, nil
+}
@@ -0,0 +1,115 @@
+//go:build linux
+// +build linux
+
+package monitoring
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestReadResourceStats(t *testing.T) {
+	stats, err := readResourceStats(os.Getpid())
+	if err != nil {
+		t.Fatal("failed to read stats with", err)
+	}
+
+	if stats.Pid != os.Getpid() || stats.Threads < 1 || stats.RSS <= 0 || stats.FDs < 3 || stats.CPUTime < 0 {
+		t.Error("failed to read stats of the process. Got", stats)
+	}
+
+	if _, err := readResourceStats(-1); err == nil {
+		t.Error("failed to catch an error of an unknown process")
+	}
+}
+
+func TestMonitoring_Stats(t *testing.T) {
+	monitoring := NewMonitoring(&testParameter{
+		command:       "sh",
+		args:          []string{"-c", "echo started; sleep 10"},
+		runningMode:   RepeatInfinity,
+		parallelCount: 2,
+		resources:     20 * time.Millisecond,
+	})
+
+	if stats := monitoring.Stats(); len(stats) != 0 {
+		t.Error("failed to get stats before start. Got", stats)
+	}
+
+	events, unsubscribe := monitoring.Subscribe(100)
+	defer unsubscribe()
+
+	monitoring.Start(context.Background())
+	defer monitoring.Stop(context.Background())
+
+	sampled := map[int]*ResourceStats{}
+
+	for len(sampled) < 2 {
+		select {
+		case event := <-events:
+			if event.Type == EventResources {
+				sampled[event.Instance] = event.Stats
+			}
+
+		case <-time.After(5 * time.Second):
+			t.Fatal("failed to wait for stats of instances. Got", sampled)
+		}
+	}
+
+	status := monitoring.Status()
+
+	for i, stats := range monitoring.Stats() {
+		if stats.Instance != i || stats.Pid != status.Instances[i].Pid || stats.Threads < 1 || stats.RSS <= 0 || stats.FDs < 1 {
+			t.Error("failed to sample stats of an instance. Got", stats)
+		}
+
+		if event := sampled[i]; event == nil || event.Pid != status.Instances[i].Pid {
+			t.Error("failed to emit stats of an instance. Got", event)
+		}
+	}
+}
+
+func TestMonitoring_ResourceLimits(t *testing.T) {
+	monitoring := NewMonitoring(&testParameter{
+		command:       "sh",
+		args:          []string{"-c", "echo started; sleep 10"},
+		runningMode:   RepeatInfinity,
+		parallelCount: 1,
+		resources:     20 * time.Millisecond,
+		limits:        &ResourceLimits{MaxRSS: 1},
+	})
+
+	events, unsubscribe := monitoring.Subscribe(100)
+	defer unsubscribe()
+
+	monitoring.Start(context.Background())
+	defer monitoring.Stop(context.Background())
+
+	var exceeded error
+
+	// the instance is killed and started again
+	for matched := 0; matched < 2; {
+		select {
+		case event := <-events:
+			switch event.Type {
+			case EventLimitExceeded:
+				exceeded = event.Err
+			case EventStartLineMatched:
+				matched++
+			}
+
+		case <-time.After(5 * time.Second):
+			t.Fatal("failed to wait for a restart")
+		}
+	}
+
+	if exceeded == nil {
+		t.Error("failed to report an exceeded limit")
+	}
+
+	if err := monitoring.HasError(); err != nil {
+		t.Error("failed to restart an instance with", err)
+	}
+}
@@ -0,0 +1,12 @@
+//go:build !linux
+// +build !linux
+
+package monitoring
+
+import (
+	"github.com/pkg/errors"
+)
+
+func readResourceStats(pid int) (ResourceStats, error) {
+	return ResourceStats{Pid: pid}, errors.New("failed to sample resources - /proc is supported on linux only")
+}
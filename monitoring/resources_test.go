@@ -0,0 +1,66 @@
+package monitoring
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCPUWindow(t *testing.T) {
+	var (
+		window cpuWindow
+		start  = time.Now()
+	)
+
+	sample := func(pid int, after, cpu time.Duration) ResourceStats {
+		return ResourceStats{Pid: pid, Time: start.Add(after), CPUTime: cpu}
+	}
+
+	if _, ok := window.percent(sample(1, 0, 0), time.Second); ok {
+		t.Error("failed to wait for a sampled window")
+	}
+
+	if _, ok := window.percent(sample(1, 500*time.Millisecond, 500*time.Millisecond), time.Second); ok {
+		t.Error("failed to wait for a sampled window")
+	}
+
+	if percent, ok := window.percent(sample(1, time.Second, time.Second), time.Second); !ok || percent != 100 {
+		t.Error("failed to average usage. Got", percent, ok, ", but expected is", 100)
+	}
+
+	// the oldest sample covering the window is kept
+	if percent, ok := window.percent(sample(1, 2*time.Second, time.Second), time.Second); !ok || percent != 0 {
+		t.Error("failed to slide a window. Got", percent, ok, ", but expected is", 0)
+	}
+
+	if _, ok := window.percent(sample(2, 3*time.Second, 0), time.Second); ok {
+		t.Error("failed to start a new window for a new run")
+	}
+}
+
+func TestResourceLimits_Check(t *testing.T) {
+	limits := ResourceLimits{MaxRSS: 1000, MaxFDs: 10, MaxCPUPercent: 50, CPUWindow: time.Second}
+	start := time.Now()
+
+	testSuites := []struct {
+		stats    ResourceStats
+		exceeded bool
+	}{
+		{stats: ResourceStats{Pid: 1, Time: start, RSS: 1000, FDs: 10}},
+		{stats: ResourceStats{Pid: 1, Time: start, RSS: 1001, FDs: 10}, exceeded: true},
+		{stats: ResourceStats{Pid: 1, Time: start, RSS: 1000, FDs: 11}, exceeded: true},
+		{stats: ResourceStats{Pid: 1, Time: start.Add(time.Second), RSS: 1000, FDs: 10, CPUTime: 400 * time.Millisecond}},
+		{stats: ResourceStats{Pid: 1, Time: start.Add(2 * time.Second), RSS: 1000, FDs: 10, CPUTime: time.Second}, exceeded: true},
+	}
+
+	var cpu cpuWindow
+
+	for i, test := range testSuites {
+		if err := limits.check(test.stats, &cpu); (err != nil) != test.exceeded {
+			t.Error(i, ": failed to check limits. Got", err, ", but expected is exceeded", test.exceeded)
+		}
+	}
+
+	if err := (ResourceLimits{}).check(ResourceStats{RSS: 1 << 40, FDs: 1 << 20}, &cpu); err != nil {
+		t.Error("failed to skip zero limits. Got", err)
+	}
+}
@@ -0,0 +1,181 @@
+package monitoring
+
+import (
+	"math"
+	"math/rand"
+	"os"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// RestartMode defines which exits of a command lead to restart it like Restart= of systemd.
+type RestartMode int32
+
+const (
+	// RestartAlways restarts a command regardless of how it exited.
+	RestartAlways RestartMode = iota
+	// RestartOnFailure restarts a command exited with an unsuccessful code or by an unclean signal.
+	RestartOnFailure
+	// RestartOnAbnormal restarts a command terminated by an unclean signal only.
+	RestartOnAbnormal
+)
+
+var restartModeNames = map[string]RestartMode{
+	"always":      RestartAlways,
+	"on-failure":  RestartOnFailure,
+	"on-abnormal": RestartOnAbnormal,
+}
+
+// ParseRestartMode parses names of systemd: always, on-failure or on-abnormal.
+func ParseRestartMode(name string) (RestartMode, error) {
+	mode, ok := restartModeNames[strings.ToLower(name)]
+	if !ok {
+		return RestartAlways, errors.Errorf("unknown restart mode '%s'", name)
+	}
+
+	return mode, nil
+}
+
+// RestartRuleParameter is an optional extension of MonitoringParameter.
+// Exit code 0 is always successful; SuccessExitCodes adds other ones.
+type RestartRuleParameter interface {
+	RestartMode() RestartMode
+	SuccessExitCodes() []int
+}
+
+// cleanSignals don't mean a failure of a command the same as systemd
+var cleanSignals = []os.Signal{syscall.SIGHUP, syscall.SIGINT, syscall.SIGTERM, syscall.SIGPIPE}
+
+const (
+	defaultRestartFactor = 2.0
+)
+
+// A RestartPolicy defines a pause before re-running a finished command.
+type RestartPolicy interface {
+	// Delay returns a pause before the attempt-th restart in a row (starts from 1).
+	Delay(attempt int32) time.Duration
+	// Healthy reports that a command ran long enough to reset the attempts counter.
+	Healthy(uptime time.Duration) bool
+}
+
+// RestartParameter is an optional extension of MonitoringParameter.
+// A nil policy means to restart a command immediately.
+type RestartParameter interface {
+	RestartPolicy() RestartPolicy
+}
+
+func (o RestartMode) NeedRestart(code int, sig os.Signal, successCodes ...int) bool {
+	switch o {
+	case RestartOnFailure:
+		if sig != nil {
+			return !isCleanSignal(sig)
+		}
+
+		return !isSuccessCode(code, successCodes...)
+
+	case RestartOnAbnormal:
+		return sig != nil && !isCleanSignal(sig)
+
+	default:
+		return true
+	}
+}
+
+func isCleanSignal(sig os.Signal) bool {
+	for _, clean := range cleanSignals {
+		if sig == clean {
+			return true
+		}
+	}
+
+	return false
+}
+
+func isSuccessCode(code int, successCodes ...int) bool {
+	if code == 0 {
+		return true
+	}
+
+	for _, success := range successCodes {
+		if code == success {
+			return true
+		}
+	}
+
+	return false
+}
+
+type restartPolicy struct {
+	initial    time.Duration
+	max        time.Duration
+	factor     float64
+	jitter     float64
+	resetAfter time.Duration
+}
+
+func ConstantRestart(delay time.Duration) *restartPolicy {
+	return &restartPolicy{
+		initial: delay,
+		max:     delay,
+		factor:  1,
+	}
+}
+
+func ExponentialRestart(initial, max time.Duration) *restartPolicy {
+	if max < initial {
+		max = initial
+	}
+
+	return &restartPolicy{
+		initial: initial,
+		max:     max,
+		factor:  defaultRestartFactor,
+	}
+}
+
+// WithFactor changes a multiplier applied to a delay on every next attempt.
+func (o *restartPolicy) WithFactor(factor float64) *restartPolicy {
+	if factor >= 1 {
+		o.factor = factor
+	}
+
+	return o
+}
+
+// WithJitter randomizes a delay by +/- part of it; the part is in [0, 1].
+func (o *restartPolicy) WithJitter(jitter float64) *restartPolicy {
+	o.jitter = math.Max(0, math.Min(1, jitter))
+
+	return o
+}
+
+// WithResetAfter resets the attempts counter if a command lived longer than healthy.
+func (o *restartPolicy) WithResetAfter(healthy time.Duration) *restartPolicy {
+	o.resetAfter = healthy
+
+	return o
+}
+
+func (o *restartPolicy) Delay(attempt int32) time.Duration {
+	if attempt < 1 {
+		attempt = 1
+	}
+
+	delay := float64(o.initial) * math.Pow(o.factor, float64(attempt-1))
+	if delay > float64(o.max) {
+		delay = float64(o.max)
+	}
+
+	if o.jitter > 0 {
+		delay += delay * o.jitter * (2*rand.Float64() - 1)
+	}
+
+	return time.Duration(math.Max(0, math.Min(delay, float64(o.max))))
+}
+
+func (o *restartPolicy) Healthy(uptime time.Duration) bool {
+	return o.resetAfter > 0 && uptime >= o.resetAfter
+}
@@ -0,0 +1,128 @@
+package monitoring
+
+import (
+	"os"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestConstantRestart(t *testing.T) {
+	expected := 50 * time.Millisecond
+	policy := ConstantRestart(expected)
+
+	for attempt := int32(0); attempt < 5; attempt++ {
+		if exist := policy.Delay(attempt); exist != expected {
+			t.Error("failed to calculate constant delay for attempt", attempt, ". Got", exist, ", but expected is", expected)
+		}
+	}
+
+	if policy.Healthy(time.Hour) {
+		t.Error("failed to check healthy without reset duration")
+	}
+}
+
+func TestExponentialRestart(t *testing.T) {
+	policy := ExponentialRestart(10*time.Millisecond, 50*time.Millisecond)
+
+	suites := []struct {
+		attempt  int32
+		expected time.Duration
+	}{
+		{1, 10 * time.Millisecond},
+		{2, 20 * time.Millisecond},
+		{3, 40 * time.Millisecond},
+		{4, 50 * time.Millisecond},
+		{100, 50 * time.Millisecond},
+	}
+
+	for _, test := range suites {
+		if exist := policy.Delay(test.attempt); exist != test.expected {
+			t.Error("failed to calculate delay for attempt", test.attempt, ". Got", exist, ", but expected is", test.expected)
+		}
+	}
+
+	policy.WithFactor(3)
+	if exist, expected := policy.Delay(2), 30*time.Millisecond; exist != expected {
+		t.Error("failed to apply factor. Got", exist, ", but expected is", expected)
+	}
+}
+
+func TestRestartPolicy_Jitter(t *testing.T) {
+	policy := ExponentialRestart(100*time.Millisecond, time.Second).WithJitter(0.5)
+
+	for i := 0; i < 100; i++ {
+		if exist := policy.Delay(1); exist < 50*time.Millisecond || exist > 150*time.Millisecond {
+			t.Error("failed to keep jitter in range. Got", exist)
+		}
+
+		if exist := policy.Delay(10); exist > time.Second {
+			t.Error("failed to cap a delay with jitter. Got", exist)
+		}
+	}
+}
+
+func TestRestartPolicy_Healthy(t *testing.T) {
+	policy := ConstantRestart(time.Millisecond).WithResetAfter(time.Second)
+
+	if policy.Healthy(100 * time.Millisecond) {
+		t.Error("failed to check a short run as unhealthy")
+	}
+
+	if !policy.Healthy(2 * time.Second) {
+		t.Error("failed to check a long run as healthy")
+	}
+}
+
+func TestRestartMode_NeedRestart(t *testing.T) {
+	suites := []struct {
+		mode     RestartMode
+		code     int
+		sig      os.Signal
+		success  []int
+		expected bool
+	}{
+		{RestartAlways, 0, nil, nil, true},
+		{RestartAlways, 1, nil, nil, true},
+		{RestartAlways, -1, syscall.SIGTERM, nil, true},
+		{RestartOnFailure, 0, nil, nil, false},
+		{RestartOnFailure, 1, nil, nil, true},
+		{RestartOnFailure, 2, nil, []int{2, 3}, false},
+		{RestartOnFailure, -1, syscall.SIGTERM, nil, false},
+		{RestartOnFailure, -1, syscall.SIGKILL, nil, true},
+		{RestartOnFailure, -1, syscall.SIGSEGV, nil, true},
+		{RestartOnAbnormal, 0, nil, nil, false},
+		{RestartOnAbnormal, 1, nil, nil, false},
+		{RestartOnAbnormal, -1, syscall.SIGINT, nil, false},
+		{RestartOnAbnormal, -1, syscall.SIGKILL, nil, true},
+	}
+
+	for _, test := range suites {
+		if exist := test.mode.NeedRestart(test.code, test.sig, test.success...); exist != test.expected {
+			t.Error("failed to check restart for mode", test.mode, ", code", test.code, ", signal", test.sig, ". Got", exist, ", but expected is", test.expected)
+		}
+	}
+}
+
+func TestParseRestartMode(t *testing.T) {
+	testSuites := []struct {
+		name     string
+		expected RestartMode
+	}{
+		{name: "always", expected: RestartAlways},
+		{name: "on-failure", expected: RestartOnFailure},
+		{name: "On-Abnormal", expected: RestartOnAbnormal},
+	}
+
+	for _, test := range testSuites {
+		if exist, err := ParseRestartMode(test.name); err != nil {
+			t.Error("failed to parse", test.name, "with", err)
+		} else if exist != test.expected {
+			t.Error("failed to parse", test.name, ". Got", exist, ", but expected is", test.expected)
+		}
+	}
+
+	if _, err := ParseRestartMode("never"); err == nil {
+		t.Error("failed to catch an error of an unknown mode")
+	}
+}
@@ -0,0 +1,90 @@
+//go:build linux
+// +build linux
+
+package monitoring
+
+import (
+	"os/exec"
+	"runtime"
+	"syscall"
+	"unsafe"
+
+	"github.com/pkg/errors"
+
+	"github.com/7phs/tools/common"
+)
+
+const rlimitSupported = true
+
+var rlimitResources = [...]int{
+	rlimitNoFile: syscall.RLIMIT_NOFILE,
+	rlimitCore:   syscall.RLIMIT_CORE,
+	rlimitAS:     syscall.RLIMIT_AS,
+}
+
+// startWithRlimits starts a command traced, so it stops right after exec before any code of the command.
+// Limits are set while it is stopped, then it is detached and runs with them from the start.
+func startWithRlimits(cmd *exec.Cmd, attrs *ProcessAttrs) error {
+	if attrs == nil || !attrs.hasLimits() {
+		return cmd.Start()
+	}
+
+	// a tracer is the thread which has started a command, every request of ptrace comes from it
+	runtime.LockOSThread()
+	defer runtime.UnlockOSThread()
+
+	if cmd.SysProcAttr == nil {
+		cmd.SysProcAttr = &syscall.SysProcAttr{}
+	}
+	cmd.SysProcAttr.Ptrace = true
+
+	if err := cmd.Start(); err != nil {
+		return errors.Wrap(err, "failed to start command traced to set limits, ptrace(2) could be restricted by Yama or seccomp")
+	}
+
+	var (
+		pid    = cmd.Process.Pid
+		status syscall.WaitStatus
+		abort  = func() {
+			cmd.Process.Kill()
+			cmd.Wait()
+		}
+	)
+
+	if _, err := syscall.Wait4(pid, &status, 0, nil); err != nil {
+		abort()
+		return errors.Wrap(err, "failed to wait for command at exec")
+	}
+
+	if !status.Stopped() {
+		abort()
+		return errors.Errorf("command %d has ended at exec", pid)
+	}
+
+	err := common.SeveralErrors("failed to start command with limits",
+		setRlimits(pid, attrs),
+		errors.Wrap(syscall.PtraceDetach(pid), "failed to detach"),
+	)
+	if err != nil {
+		abort()
+	}
+
+	return err
+}
+
+// setRlimits sets limits of a process by prlimit(2), every failed limit is reported.
+func setRlimits(pid int, attrs *ProcessAttrs) error {
+	var errs []error
+
+	for _, limit := range attrs.limits() {
+		value := syscall.Rlimit{Cur: limit.Soft, Max: limit.Hard}
+
+		_, _, errno := syscall.RawSyscall6(syscall.SYS_PRLIMIT64, uintptr(pid), uintptr(rlimitResources[limit.resource]),
+			uintptr(unsafe.Pointer(&value)), 0, 0, 0)
+		if errno != 0 {
+			errs = append(errs, errors.Wrapf(errno, "failed to set a limit of %s", limit.name))
+		}
+	}
+
+	return common.SeveralErrors("failed to set limits of resources", errs...)
+}
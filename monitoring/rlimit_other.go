@@ -0,0 +1,15 @@
+//go:build !linux
+// +build !linux
+
+package monitoring
+
+import (
+	"os/exec"
+)
+
+const rlimitSupported = false
+
+// startWithRlimits starts a command as is, limits are rejected by a check of attributes.
+func startWithRlimits(cmd *exec.Cmd, _ *ProcessAttrs) error {
+	return cmd.Start()
+}
@@ -0,0 +1,137 @@
+package monitoring
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+
+	"github.com/pkg/errors"
+
+	"github.com/7phs/tools/common"
+)
+
+// Scale changes a count of parallel instances of active monitoring. New instances are started
+// and checked the same as by Start, so they are added only if all of them are ready.
+// Surplus instances with the highest indexes are stopped the same as by StopInstance and removed.
+func (o *Monitoring) Scale(ctx context.Context, count int) error {
+	return o.waitCommand(context.WithValue(ctx, "count", count), cmdScale)
+}
+
+func (o *Monitoring) commandScale(ctx context.Context) error {
+	if !o.isRunning() {
+		return errors.Errorf("failed to execute %v - monitoring isn't active", cmdScale)
+	}
+
+	count, _ := ctx.Value("count").(int)
+	if count <= 0 {
+		return errors.Errorf("failed to scale to %d instances - at least one is required", count)
+	}
+
+	cmds := o.commands()
+
+	switch {
+	case count > len(cmds):
+		return errors.Wrapf(o.scaleUp(ctx, len(cmds), count), "failed to scale to %d instances", count)
+
+	case count < len(cmds):
+		return errors.Wrapf(o.scaleDown(ctx, cmds[count:]), "failed to scale to %d instances", count)
+	}
+
+	return nil
+}
+
+func (o *Monitoring) scaleUp(ctx context.Context, from, to int) (err error) {
+	added := make([]*commandState, 0, to-from)
+
+	for i := from; i < to; i++ {
+		cmd, cmdErr := o.newCommand(i)
+		if cmdErr != nil {
+			return cmdErr
+		}
+
+		added = append(added, cmd)
+	}
+
+	wait := make(chan error)
+	for _, cmd := range added {
+		go cmd.Run(ctx, wait)
+	}
+
+	for range added {
+		if cmdErr := <-wait; cmdErr != nil {
+			err = common.SeveralErrors("failed to start command:", err, cmdErr)
+		}
+	}
+
+	// all or nothing, running instances are kept as is
+	if err != nil {
+		for _, cmd := range added {
+			if !cmd.isFinished() {
+				cmd.Kill()
+				cmd.emit(EventKilled, nil)
+			}
+
+			o.releasePort(cmd)
+		}
+
+		return err
+	}
+
+	o.cmdLock.Lock()
+	o.cmd = append(o.cmd, added...)
+	o.cmdLock.Unlock()
+
+	for _, cmd := range added {
+		o.monitorInstance(cmd)
+		o.watchInstance(cmd)
+	}
+
+	return nil
+}
+
+func (o *Monitoring) scaleDown(ctx context.Context, surplus []*commandState) error {
+	var wait sync.WaitGroup
+
+	errs := make([]error, len(surplus))
+
+	for i, cmd := range surplus {
+		wait.Add(1)
+		go func(i int, cmd *commandState) {
+			defer wait.Done()
+
+			errs[i] = o.stopInstance(ctx, cmd)
+		}(i, cmd)
+	}
+
+	wait.Wait()
+
+	// stopped instances are removed anyway, a failed graceful stop has killed them
+	o.cmdLock.Lock()
+	o.cmd = o.cmd[:len(o.cmd)-len(surplus)]
+	o.cmdLock.Unlock()
+
+	for _, cmd := range surplus {
+		if atomic.CompareAndSwapInt32(&cmd.finished, 1, 0) {
+			atomic.AddInt32(&o.finished, -1)
+		}
+
+		if cmd.removed != nil {
+			close(cmd.removed)
+		}
+
+		o.releasePort(cmd)
+	}
+
+	// the rest instances could have finished without a restart
+	if int(atomic.LoadInt32(&o.finished)) >= len(o.commands()) {
+		go o.Stop(context.Background())
+	}
+
+	return common.SeveralErrors("failed to stop instances", errs...)
+}
+
+func (o *Monitoring) releasePort(cmd *commandState) {
+	if o.ports != nil {
+		o.ports.release(cmd.index)
+	}
+}
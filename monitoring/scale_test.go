@@ -0,0 +1,114 @@
+package monitoring
+
+import (
+	"context"
+	"testing"
+)
+
+func TestMonitoring_Scale(t *testing.T) {
+	monitoring := NewMonitoring(&testParameter{
+		command:       "sh",
+		args:          []string{"-c", "echo started; sleep 10"},
+		runningMode:   RepeatInfinity,
+		parallelCount: 1,
+	})
+
+	if err := monitoring.Scale(context.Background(), 2); err == nil {
+		t.Error("failed to catch an error of inactive monitoring")
+	}
+
+	monitoring.Start(context.Background())
+	defer monitoring.Stop(context.Background())
+
+	if err := monitoring.Scale(context.Background(), 0); err == nil {
+		t.Error("failed to catch an error of an invalid count")
+	}
+
+	if err := monitoring.Scale(context.Background(), 3); err != nil {
+		t.Error("failed to scale up with", err)
+	}
+
+	status := monitoring.Status()
+	if count := len(status.Instances); count != 3 {
+		t.Error("failed to add instances. Got", count, ", but expected is", 3)
+	}
+
+	for i, instance := range status.Instances {
+		if instance.Index != i || instance.Exited || instance.Runs != 1 {
+			t.Error("failed to start an added instance. Got", instance)
+		}
+	}
+
+	events, unsubscribe := monitoring.Subscribe(100)
+	cmds := monitoring.commands()
+
+	if err := monitoring.Scale(context.Background(), 1); err != nil {
+		t.Error("failed to scale down with", err)
+	}
+	unsubscribe()
+
+	killed := map[int]bool{}
+	for event := range events {
+		if event.Type == EventKilled {
+			killed[event.Instance] = true
+		}
+	}
+
+	if len(killed) != 2 || !killed[1] || !killed[2] {
+		t.Error("failed to stop surplus instances. Got", killed)
+	}
+
+	// checks of removed instances are over
+	for _, cmd := range cmds[1:] {
+		select {
+		case <-cmd.removed:
+		default:
+			t.Error("failed to stop checks of a removed instance #", cmd.index)
+		}
+	}
+
+	select {
+	case <-cmds[0].removed:
+		t.Error("failed to keep checks of a running instance")
+	default:
+	}
+
+	status = monitoring.Status()
+	if count := len(status.Instances); count != 1 {
+		t.Error("failed to remove instances. Got", count, ", but expected is", 1)
+	} else if instance := status.Instances[0]; instance.Exited || instance.Runs != 1 {
+		t.Error("failed to keep a running instance. Got", instance)
+	}
+
+	if err := monitoring.HasError(); err != nil {
+		t.Error("failed to run monitoring with", err)
+	}
+}
+
+func TestMonitoring_ScaleFailure(t *testing.T) {
+	monitoring := NewMonitoring(&testParameter{
+		command:       "sh",
+		args:          []string{"-c", "if [ $" + EnvInstance + " -gt 1 ]; then echo failed >&2; sleep 10; fi; echo started; sleep 10"},
+		runningMode:   RepeatInfinity,
+		parallelCount: 1,
+	})
+
+	monitoring.Start(context.Background())
+	defer monitoring.Stop(context.Background())
+
+	if err := monitoring.Scale(context.Background(), 3); err == nil {
+		t.Error("failed to catch an error of a start")
+	}
+
+	if count := len(monitoring.Status().Instances); count != 1 {
+		t.Error("failed to keep instances after a failed start. Got", count, ", but expected is", 1)
+	}
+
+	if err := monitoring.HasError(); err != nil {
+		t.Error("failed to keep an error of scaling from monitoring. Got", err)
+	}
+
+	if err := monitoring.Scale(context.Background(), 2); err != nil {
+		t.Error("failed to scale up after a failure with", err)
+	}
+}
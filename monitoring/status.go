@@ -0,0 +1,48 @@
+package monitoring
+
+import (
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// InstanceStatus is a state of a parallel instance, Pid is of the last run.
+type InstanceStatus struct {
+	Index     int       `json:"index"`
+	Pid       int       `json:"pid"`
+	Port      int       `json:"port,omitempty"`
+	Runs      int32     `json:"runs"`
+	StartTime time.Time `json:"start_time"`
+	Exited    bool      `json:"exited"`
+	Unhealthy bool      `json:"unhealthy"`
+	Stopped   bool      `json:"stopped"` // by StopInstance
+}
+
+// Status is a state of monitoring: stopped, starting, monitoring or finish.
+type Status struct {
+	Stage     string           `json:"stage"`
+	Error     string           `json:"error,omitempty"`
+	Instances []InstanceStatus `json:"instances"`
+}
+
+func (o monitoringStage) status() string {
+	return strings.ToLower(strings.TrimPrefix(o.String(), "exec"))
+}
+
+func (o *Monitoring) Status() Status {
+	status := Status{
+		Stage: monitoringStage(atomic.LoadInt32(&o.stage)).status(),
+	}
+
+	if err := o.HasError(); err != nil {
+		status.Error = err.Error()
+	}
+
+	for _, cmd := range o.commands() {
+		if cmd != nil {
+			status.Instances = append(status.Instances, cmd.status())
+		}
+	}
+
+	return status
+}
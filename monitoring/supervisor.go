@@ -0,0 +1,342 @@
+package monitoring
+
+import (
+	"context"
+	"strings"
+	"sync"
+
+	"github.com/pkg/errors"
+
+	"github.com/7phs/tools/common"
+)
+
+type program struct {
+	parameter  MonitoringParameter
+	requires   []string
+	after      []string
+	monitoring *Monitoring
+}
+
+// dependencies are programs to start before.
+func (o *program) dependencies() []string {
+	return append(append([]string(nil), o.requires...), o.after...)
+}
+
+// Supervisor manages several named programs like supervisord. Every start of a program
+// creates a new Monitoring, because a stopped one can't be started again.
+type Supervisor struct {
+	sync.Mutex
+
+	programs map[string]*program
+	order    []string
+
+	running sync.WaitGroup
+}
+
+func NewSupervisor() *Supervisor {
+	return &Supervisor{
+		programs: make(map[string]*program),
+	}
+}
+
+// Add registers a program which requires the listed ones like Requires.
+// The listed programs must be added before.
+func (o *Supervisor) Add(name string, parameter MonitoringParameter, requires ...string) error {
+	o.Lock()
+	defer o.Unlock()
+
+	if name == "" {
+		return errors.New("failed to add a program without a name")
+	}
+
+	if _, ok := o.programs[name]; ok {
+		return errors.Errorf("failed to add a program '%s' - already exists", name)
+	}
+
+	if err := o.checkNames(requires); err != nil {
+		return errors.Wrapf(err, "failed to add a program '%s'", name)
+	}
+
+	o.programs[name] = &program{
+		parameter: parameter,
+		requires:  requires,
+	}
+	o.order = append(o.order, name)
+
+	return nil
+}
+
+// Requires declares a program starts the listed ones before itself and after they are ready
+// (have passed their start line check). A program isn't started if a required one has failed.
+func (o *Supervisor) Requires(name string, requires ...string) error {
+	o.Lock()
+	defer o.Unlock()
+
+	prg, err := o.declare(name, requires)
+	if err != nil {
+		return err
+	}
+
+	prg.requires = append(prg.requires, requires...)
+
+	return nil
+}
+
+// After declares a program starts after the listed ones when they are started together,
+// but doesn't start them.
+func (o *Supervisor) After(name string, after ...string) error {
+	o.Lock()
+	defer o.Unlock()
+
+	prg, err := o.declare(name, after)
+	if err != nil {
+		return err
+	}
+
+	prg.after = append(prg.after, after...)
+
+	return nil
+}
+
+func (o *Supervisor) declare(name string, dependencies []string) (*program, error) {
+	prg, ok := o.programs[name]
+	if !ok {
+		return nil, errors.Errorf("failed to declare dependencies of an unknown program '%s'", name)
+	}
+
+	return prg, errors.Wrapf(o.checkNames(dependencies), "failed to declare dependencies of '%s'", name)
+}
+
+func (o *Supervisor) checkNames(names []string) error {
+	for _, name := range names {
+		if _, ok := o.programs[name]; !ok {
+			return errors.Errorf("unknown dependency '%s'", name)
+		}
+	}
+
+	return nil
+}
+
+// Names returns names of programs in order of adding.
+func (o *Supervisor) Names() []string {
+	o.Lock()
+	defer o.Unlock()
+
+	return append([]string(nil), o.order...)
+}
+
+// Program returns the current Monitoring of a program or nil if it has never been started.
+func (o *Supervisor) Program(name string) *Monitoring {
+	o.Lock()
+	defer o.Unlock()
+
+	if prg, ok := o.programs[name]; ok {
+		return prg.monitoring
+	}
+
+	return nil
+}
+
+// Start starts all stopped programs after their dependencies, it breaks on the first failed one.
+// Cycles of dependencies are reported before starting anything.
+func (o *Supervisor) Start(ctx context.Context) error {
+	o.Lock()
+	defer o.Unlock()
+
+	order, err := o.startOrder()
+	if err != nil {
+		return err
+	}
+
+	for _, name := range order {
+		if err := o.startProgram(ctx, name); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Stop stops all programs in reverse order of start, so dependent ones are stopped before their dependencies.
+func (o *Supervisor) Stop(ctx context.Context) error {
+	o.Lock()
+	order, err := o.startOrder()
+	if err != nil {
+		// nothing has been started with cycles, but programs added later could be
+		order = append([]string(nil), o.order...)
+	}
+	o.Unlock()
+
+	errs := make([]error, 0, len(order))
+
+	for i := len(order) - 1; i >= 0; i-- {
+		errs = append(errs, o.StopProgram(ctx, order[i]))
+	}
+
+	return common.SeveralErrors("failed to stop programs", errs...)
+}
+
+// StartProgram starts a program and its stopped required programs before. A running program is kept as is.
+func (o *Supervisor) StartProgram(ctx context.Context, name string) error {
+	o.Lock()
+	defer o.Unlock()
+
+	if _, ok := o.programs[name]; !ok {
+		return errors.Errorf("failed to start an unknown program '%s'", name)
+	}
+
+	order, err := o.startOrder()
+	if err != nil {
+		return err
+	}
+
+	required := o.required(name, map[string]bool{})
+
+	for _, dependency := range order {
+		if !required[dependency] || dependency == name {
+			continue
+		}
+
+		if err := o.startProgram(ctx, dependency); err != nil {
+			return errors.Wrapf(err, "failed to start a dependency of '%s'", name)
+		}
+	}
+
+	return o.startProgram(ctx, name)
+}
+
+// required collects a program and all programs required by it.
+func (o *Supervisor) required(name string, required map[string]bool) map[string]bool {
+	if required[name] {
+		return required
+	}
+	required[name] = true
+
+	for _, dependency := range o.programs[name].requires {
+		o.required(dependency, required)
+	}
+
+	return required
+}
+
+// startOrder sorts programs so everyone follows its dependencies, an order of adding is kept otherwise.
+func (o *Supervisor) startOrder() ([]string, error) {
+	const (
+		visiting = iota + 1
+		visited
+	)
+
+	var (
+		state = make(map[string]int, len(o.order))
+		order = make([]string, 0, len(o.order))
+		path  []string
+		visit func(name string) error
+	)
+
+	visit = func(name string) error {
+		switch state[name] {
+		case visited:
+			return nil
+
+		case visiting:
+			for i := range path {
+				if path[i] == name {
+					return errors.Errorf("cycle of dependencies: %s", strings.Join(append(path[i:], name), " -> "))
+				}
+			}
+		}
+
+		state[name] = visiting
+		path = append(path, name)
+
+		for _, dependency := range o.programs[name].dependencies() {
+			if err := visit(dependency); err != nil {
+				return err
+			}
+		}
+
+		path = path[:len(path)-1]
+		state[name] = visited
+		order = append(order, name)
+
+		return nil
+	}
+
+	for _, name := range o.order {
+		if err := visit(name); err != nil {
+			return nil, err
+		}
+	}
+
+	return order, nil
+}
+
+func (o *Supervisor) startProgram(ctx context.Context, name string) error {
+	prg := o.programs[name]
+	if prg.monitoring.isRunning() {
+		return nil
+	}
+
+	monitoring := NewMonitoring(prg.parameter)
+	prg.monitoring = monitoring
+
+	o.running.Add(1)
+	go func() {
+		defer o.running.Done()
+
+		monitoring.Wait()
+	}()
+
+	monitoring.Start(ctx)
+
+	return errors.Wrapf(monitoring.HasError(), "failed to start a program '%s'", name)
+}
+
+// StopProgram stops a program only, dependent ones keep running.
+func (o *Supervisor) StopProgram(ctx context.Context, name string) error {
+	o.Lock()
+	prg, ok := o.programs[name]
+	if !ok {
+		o.Unlock()
+		return errors.Errorf("failed to stop an unknown program '%s'", name)
+	}
+	monitoring := prg.monitoring
+	o.Unlock()
+
+	if !monitoring.isRunning() {
+		return nil
+	}
+
+	monitoring.Stop(ctx)
+
+	return errors.Wrapf(monitoring.HasError(), "failed to stop a program '%s'", name)
+}
+
+func (o *Supervisor) RestartProgram(ctx context.Context, name string) error {
+	if err := o.StopProgram(ctx, name); err != nil {
+		return err
+	}
+
+	return o.StartProgram(ctx, name)
+}
+
+// Wait blocks till all started programs have finished.
+func (o *Supervisor) Wait() {
+	o.running.Wait()
+}
+
+// HasError returns errors of the last runs of all programs.
+func (o *Supervisor) HasError() error {
+	o.Lock()
+	defer o.Unlock()
+
+	errs := make([]error, 0, len(o.order))
+
+	for _, name := range o.order {
+		if monitoring := o.programs[name].monitoring; monitoring != nil {
+			errs = append(errs, errors.Wrapf(monitoring.HasError(), "program '%s'", name))
+		}
+	}
+
+	return common.SeveralErrors("programs failed", errs...)
+}
@@ -0,0 +1,235 @@
+package monitoring
+
+import (
+	"context"
+	"reflect"
+	"sync"
+	"testing"
+)
+
+func TestSupervisor_Add(t *testing.T) {
+	supervisor := NewSupervisor()
+
+	if err := supervisor.Add("db", &testParameter{command: "true"}); err != nil {
+		t.Error("failed to add a program with", err)
+	}
+
+	if err := supervisor.Add("api", &testParameter{command: "true"}, "db"); err != nil {
+		t.Error("failed to add a program with a dependency with", err)
+	}
+
+	if err := supervisor.Add("", &testParameter{command: "true"}); err == nil {
+		t.Error("failed to catch an error of an empty name")
+	}
+
+	if err := supervisor.Add("db", &testParameter{command: "true"}); err == nil {
+		t.Error("failed to catch an error of a duplicated name")
+	}
+
+	if err := supervisor.Add("worker", &testParameter{command: "true"}, "queue"); err == nil {
+		t.Error("failed to catch an error of an unknown dependency")
+	}
+
+	if exist, expected := supervisor.Names(), []string{"db", "api"}; !reflect.DeepEqual(exist, expected) {
+		t.Error("failed to keep an order of programs. Got", exist, ", but expected is", expected)
+	}
+}
+
+func TestSupervisor_Start(t *testing.T) {
+	var (
+		lock  sync.Mutex
+		lines []string
+	)
+
+	program := func(name string) *testParameter {
+		return &testParameter{
+			command:     "sh",
+			args:        []string{"-c", "sleep 0.1; echo " + name + "; sleep 10"},
+			runningMode: RunOnce,
+			stdoutSink: OutputSinkFunc(func(_ int, line string) {
+				lock.Lock()
+				lines = append(lines, line)
+				lock.Unlock()
+			}),
+		}
+	}
+
+	supervisor := NewSupervisor()
+	supervisor.Add("db", program("db"))
+	supervisor.Add("api", program("api"), "db")
+	supervisor.Add("worker", program("worker"), "api")
+
+	// dependencies are started before
+	if err := supervisor.StartProgram(context.Background(), "worker"); err != nil {
+		t.Error("failed to start a program with", err)
+	}
+
+	lock.Lock()
+	if expected := []string{"db\n", "api\n", "worker\n"}; !reflect.DeepEqual(lines, expected) {
+		t.Error("failed to start programs in order. Got", lines, ", but expected is", expected)
+	}
+	lock.Unlock()
+
+	monitoring := supervisor.Program("api")
+	if err := supervisor.RestartProgram(context.Background(), "api"); err != nil {
+		t.Error("failed to restart a program with", err)
+	}
+
+	if restarted := supervisor.Program("api"); restarted == monitoring || !restarted.isRunning() {
+		t.Error("failed to restart a program")
+	}
+
+	if err := supervisor.StopProgram(context.Background(), "db"); err != nil {
+		t.Error("failed to stop a program with", err)
+	}
+
+	if supervisor.Program("db").isRunning() || !supervisor.Program("worker").isRunning() {
+		t.Error("failed to stop a single program")
+	}
+
+	if err := supervisor.Stop(context.Background()); err != nil {
+		t.Error("failed to stop programs with", err)
+	}
+
+	supervisor.Wait()
+
+	if err := supervisor.HasError(); err != nil {
+		t.Error("failed to run programs with", err)
+	}
+
+	if err := supervisor.StartProgram(context.Background(), "unknown"); err == nil {
+		t.Error("failed to catch an error of an unknown program")
+	}
+}
+
+func TestSupervisor_StartFailure(t *testing.T) {
+	supervisor := NewSupervisor()
+	supervisor.Add("db", &testParameter{command: "not-existing-command-for-test", runningMode: RunOnce})
+	supervisor.Add("api", &testParameter{command: "sh", args: []string{"-c", "echo api; sleep 10"}, runningMode: RunOnce}, "db")
+
+	if err := supervisor.Start(context.Background()); err == nil {
+		t.Error("failed to catch an error of a program start")
+	}
+
+	if supervisor.Program("api") != nil {
+		t.Error("failed to skip a program with a failed dependency")
+	}
+
+	supervisor.Wait()
+
+	if err := supervisor.HasError(); err == nil {
+		t.Error("failed to report an error of a program")
+	}
+}
+
+func TestSupervisor_StartOrder(t *testing.T) {
+	supervisor := NewSupervisor()
+	supervisor.Add("api", &testParameter{command: "true"})
+	supervisor.Add("worker", &testParameter{command: "true"}, "api")
+	supervisor.Add("db", &testParameter{command: "true"})
+	supervisor.Add("cache", &testParameter{command: "true"})
+
+	if err := supervisor.Requires("api", "db"); err != nil {
+		t.Error("failed to declare a required program with", err)
+	}
+
+	if err := supervisor.After("api", "cache"); err != nil {
+		t.Error("failed to declare an order of programs with", err)
+	}
+
+	if err := supervisor.After("api", "queue"); err == nil {
+		t.Error("failed to catch an error of an unknown dependency")
+	}
+
+	if err := supervisor.Requires("queue", "db"); err == nil {
+		t.Error("failed to catch an error of an unknown program")
+	}
+
+	exist, err := supervisor.startOrder()
+	if err != nil {
+		t.Error("failed to sort programs with", err)
+	}
+
+	if expected := []string{"db", "cache", "api", "worker"}; !reflect.DeepEqual(exist, expected) {
+		t.Error("failed to sort programs. Got", exist, ", but expected is", expected)
+	}
+
+	if exist, expected := supervisor.required("worker", map[string]bool{}), map[string]bool{"worker": true, "api": true, "db": true}; !reflect.DeepEqual(exist, expected) {
+		t.Error("failed to collect required programs. Got", exist, ", but expected is", expected)
+	}
+
+	supervisor.After("db", "worker")
+
+	_, err = supervisor.startOrder()
+	if err == nil {
+		t.Error("failed to catch a cycle of dependencies")
+	} else if expected := "cycle of dependencies: api -> db -> worker -> api"; err.Error() != expected {
+		t.Error("failed to report a cycle. Got", err, ", but expected is", expected)
+	}
+
+	if err := supervisor.Start(context.Background()); err == nil {
+		t.Error("failed to catch a cycle of dependencies on start")
+	}
+
+	for _, name := range supervisor.Names() {
+		if supervisor.Program(name) != nil {
+			t.Error("failed to skip a start of", name, "with a cycle of dependencies")
+		}
+	}
+}
+
+func TestSupervisor_After(t *testing.T) {
+	var (
+		lock  sync.Mutex
+		lines []string
+	)
+
+	program := func(name string) *testParameter {
+		return &testParameter{
+			command:     "sh",
+			args:        []string{"-c", "echo " + name + "; sleep 10"},
+			runningMode: RunOnce,
+			stdoutSink: OutputSinkFunc(func(_ int, line string) {
+				lock.Lock()
+				lines = append(lines, line)
+				lock.Unlock()
+			}),
+		}
+	}
+
+	supervisor := NewSupervisor()
+	supervisor.Add("api", program("api"))
+	supervisor.Add("db", program("db"))
+	supervisor.After("api", "db")
+
+	if err := supervisor.StartProgram(context.Background(), "api"); err != nil {
+		t.Error("failed to start a program with", err)
+	}
+
+	if supervisor.Program("db") != nil {
+		t.Error("failed to skip a start of an ordered, but not required program")
+	}
+
+	supervisor.StopProgram(context.Background(), "api")
+	supervisor.Wait()
+
+	lock.Lock()
+	lines = nil
+	lock.Unlock()
+
+	if err := supervisor.Start(context.Background()); err != nil {
+		t.Error("failed to start programs with", err)
+	}
+
+	lock.Lock()
+	if expected := []string{"db\n", "api\n"}; !reflect.DeepEqual(lines, expected) {
+		t.Error("failed to start programs in order. Got", lines, ", but expected is", expected)
+	}
+	lock.Unlock()
+
+	if err := supervisor.Stop(context.Background()); err != nil {
+		t.Error("failed to stop programs with", err)
+	}
+
+	supervisor.Wait()
+}
@@ -0,0 +1,123 @@
+package monitoring
+
+import (
+	"sync"
+	"time"
+)
+
+// Names of output streams.
+const (
+	StreamStdOut = "stdout"
+	StreamStdErr = "stderr"
+)
+
+const (
+	defaultTailSize = 100
+)
+
+// OutputLine is a line of an output of an instance kept for a tail.
+type OutputLine struct {
+	Instance int       `json:"instance"`
+	Stream   string    `json:"stream"`
+	Line     string    `json:"line"`
+	Time     time.Time `json:"time"`
+}
+
+// outputTail keeps last lines of both streams and passes new ones to followers.
+type outputTail struct {
+	sync.RWMutex
+
+	lines []OutputLine
+	next  int
+	full  bool
+
+	followers map[int]chan OutputLine
+	nextID    int
+}
+
+func newOutputTail(size int) *outputTail {
+	return &outputTail{
+		lines:     make([]OutputLine, size),
+		followers: make(map[int]chan OutputLine),
+	}
+}
+
+func (o *outputTail) sink(stream string) OutputSink {
+	return OutputSinkFunc(func(instance int, line string) {
+		o.add(OutputLine{
+			Instance: instance,
+			Stream:   stream,
+			Line:     line,
+			Time:     time.Now(),
+		})
+	})
+}
+
+func (o *outputTail) add(line OutputLine) {
+	o.Lock()
+	defer o.Unlock()
+
+	o.lines[o.next] = line
+	o.next = (o.next + 1) % len(o.lines)
+	o.full = o.full || o.next == 0
+
+	for _, ch := range o.followers {
+		select {
+		case ch <- line:
+		default:
+		}
+	}
+}
+
+// last returns up to count last lines from old to new ones.
+func (o *outputTail) last(count int) []OutputLine {
+	o.RLock()
+	defer o.RUnlock()
+
+	size := o.next
+	if o.full {
+		size = len(o.lines)
+	}
+
+	if count <= 0 || count > size {
+		count = size
+	}
+
+	result := make([]OutputLine, 0, count)
+	for i := o.next - count; i < o.next; i++ {
+		result = append(result, o.lines[(i+len(o.lines))%len(o.lines)])
+	}
+
+	return result
+}
+
+func (o *outputTail) follow(size int) (<-chan OutputLine, func()) {
+	o.Lock()
+	defer o.Unlock()
+
+	id, ch := o.nextID, make(chan OutputLine, size)
+	o.followers[id] = ch
+	o.nextID++
+
+	var once sync.Once
+
+	return ch, func() {
+		once.Do(func() {
+			o.Lock()
+			delete(o.followers, id)
+			close(ch)
+			o.Unlock()
+		})
+	}
+}
+
+// Tail returns up to count last lines of an output of all instances, all kept lines with count <= 0.
+func (o *Monitoring) Tail(count int) []OutputLine {
+	return o.tail.last(count)
+}
+
+// FollowOutput returns a channel of new lines of an output with a buffer of the size and a function
+// to unsubscribe. Lines are dropped while the buffer is full the same as events.
+func (o *Monitoring) FollowOutput(size int) (<-chan OutputLine, func()) {
+	return o.tail.follow(size)
+}
@@ -0,0 +1,48 @@
+package monitoring
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestOutputTail(t *testing.T) {
+	tail := newOutputTail(3)
+
+	if exist := tail.last(0); len(exist) != 0 {
+		t.Error("failed to return an empty tail. Got", exist)
+	}
+
+	lines, unsubscribe := tail.follow(10)
+
+	stdout, stderr := tail.sink(StreamStdOut), tail.sink(StreamStdErr)
+	stdout.WriteLine(0, "1\n")
+	stderr.WriteLine(1, "2\n")
+	stdout.WriteLine(0, "3\n")
+	stdout.WriteLine(1, "4\n")
+
+	text := func(lines []OutputLine) (result []string) {
+		for _, line := range lines {
+			result = append(result, line.Stream+":"+line.Line)
+		}
+		return
+	}
+
+	if exist, expected := text(tail.last(0)), []string{"stderr:2\n", "stdout:3\n", "stdout:4\n"}; !reflect.DeepEqual(exist, expected) {
+		t.Error("failed to keep last lines. Got", exist, ", but expected is", expected)
+	}
+
+	if exist, expected := text(tail.last(2)), []string{"stdout:3\n", "stdout:4\n"}; !reflect.DeepEqual(exist, expected) {
+		t.Error("failed to return a count of lines. Got", exist, ", but expected is", expected)
+	}
+
+	unsubscribe()
+
+	var followed []OutputLine
+	for line := range lines {
+		followed = append(followed, line)
+	}
+
+	if len(followed) != 4 || followed[1].Instance != 1 {
+		t.Error("failed to follow lines. Got", followed)
+	}
+}
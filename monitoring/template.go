@@ -0,0 +1,137 @@
+package monitoring
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/7phs/tools/common"
+)
+
+const (
+	templateDelim = "{{"
+)
+
+// TemplateParameter is an optional extension of MonitoringParameter to expand Command, ToArgs,
+// WorkDir and Env as text/template with TemplateData on every run of an instance.
+type TemplateParameter interface {
+	Template() bool
+	// PidDir is a directory of PID files, os.TempDir() by default.
+	PidDir() string
+}
+
+// TemplateData is passed to templates of a command. PortPlaceholder is a function
+// returning the same value as .Port, so it keeps working with templates.
+type TemplateData struct {
+	Instance int
+	Run      int32
+	Port     int
+	PidFile  string // <PidDir>/<base name of expanded Command>.<instance>.pid, empty in Command
+	Time     time.Time
+}
+
+type templateConfig struct {
+	pidDir string
+	// env is a count of variables of EnvParameter placed at the end of the environment,
+	// the rest are inherited and aren't expanded
+	env int
+}
+
+func newTemplateConfig(parameter MonitoringParameter) *templateConfig {
+	tmpl, ok := parameter.(TemplateParameter)
+	if !ok || !tmpl.Template() {
+		return nil
+	}
+
+	config := &templateConfig{
+		pidDir: tmpl.PidDir(),
+	}
+	if config.pidDir == "" {
+		config.pidDir = os.TempDir()
+	}
+
+	if env, ok := parameter.(EnvParameter); ok {
+		config.env = len(env.Env())
+	}
+
+	return config
+}
+
+func (o *templateConfig) pidFile(command string, instance int) string {
+	return filepath.Join(o.pidDir, fmt.Sprintf("%s.%d.pid", filepath.Base(command), instance))
+}
+
+func expandTemplate(value string, data TemplateData) (string, error) {
+	if !strings.Contains(value, templateDelim) {
+		return value, nil
+	}
+
+	tmpl, err := template.New(value).
+		Funcs(template.FuncMap{
+			"port": func() int { return data.Port },
+		}).
+		Parse(value)
+	if err != nil {
+		return value, errors.Wrapf(err, "failed to parse template '%s'", value)
+	}
+
+	var result strings.Builder
+	if err = tmpl.Execute(&result, data); err != nil {
+		return value, errors.Wrapf(err, "failed to execute template '%s'", value)
+	}
+
+	return result.String(), nil
+}
+
+// expandTemplates expands templates of the command of the next run.
+func (o *commandState) expandTemplates() error {
+	if o.templates == nil {
+		return nil
+	}
+
+	command := o.cmd.Args[0]
+	data := TemplateData{
+		Instance: o.index,
+		Run:      o.RunCount() + 1,
+		Port:     o.port,
+		Time:     time.Now(),
+	}
+
+	var errs []error
+	expand := func(value *string) {
+		var err error
+
+		*value, err = expandTemplate(*value, data)
+		errs = append(errs, err)
+	}
+
+	expand(&command)
+	data.PidFile = o.templates.pidFile(command, o.index)
+
+	for i := 1; i < len(o.cmd.Args); i++ {
+		expand(&o.cmd.Args[i])
+	}
+	expand(&o.cmd.Dir)
+	for i := len(o.cmd.Env) - o.templates.env; i >= 0 && i < len(o.cmd.Env); i++ {
+		expand(&o.cmd.Env[i])
+	}
+
+	if err := common.SeveralErrors("failed to expand templates of "+o.cmd.Args[0], errs...); err != nil {
+		return err
+	}
+
+	if command != o.cmd.Args[0] {
+		o.cmd.Args[0], o.cmd.Path, o.cmd.Err = command, command, nil
+		if !strings.ContainsRune(command, filepath.Separator) {
+			o.cmd.Path, o.cmd.Err = exec.LookPath(command)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,119 @@
+package monitoring
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestNewTemplateConfig(t *testing.T) {
+	if config := newTemplateConfig(&testParameter{}); config != nil {
+		t.Error("failed to skip templates by default. Got", config)
+	}
+
+	config := newTemplateConfig(&testParameter{template: true, env: []string{"A=1", "B=2"}})
+	if config == nil {
+		t.Error("failed to enable templates")
+		return
+	}
+
+	if config.pidDir != os.TempDir() {
+		t.Error("failed to use a default PID directory. Got", config.pidDir, ", but expected is", os.TempDir())
+	}
+
+	if config.env != 2 {
+		t.Error("failed to count variables to expand. Got", config.env, ", but expected is", 2)
+	}
+}
+
+func TestExpandTemplate(t *testing.T) {
+	data := TemplateData{
+		Instance: 2,
+		Run:      3,
+		Port:     8080,
+		PidFile:  "/run/app.2.pid",
+		Time:     time.Unix(1500000000, 0),
+	}
+
+	testSuites := []struct {
+		in       string
+		expected string
+	}{
+		{in: "--verbose", expected: "--verbose"},
+		{in: "--port={{port}}", expected: "--port=8080"},
+		{in: "--port={{.Port}}", expected: "--port=8080"},
+		{in: "--id={{.Instance}}-{{.Run}}", expected: "--id=2-3"},
+		{in: "--pid={{.PidFile}}", expected: "--pid=/run/app.2.pid"},
+		{in: "--start={{.Time.Unix}}", expected: "--start=1500000000"},
+	}
+
+	for i, test := range testSuites {
+		if exist, err := expandTemplate(test.in, data); err != nil {
+			t.Error(i, ": failed to expand a template with", err)
+		} else if exist != test.expected {
+			t.Error(i, ": failed to expand a template. Got", exist, ", but expected is", test.expected)
+		}
+	}
+
+	if _, err := expandTemplate("{{.Unknown}}", data); err == nil {
+		t.Error("failed to catch an error of an unknown field")
+	}
+
+	if _, err := expandTemplate("{{.Port", data); err == nil {
+		t.Error("failed to catch an error of a broken template")
+	}
+}
+
+func TestCommandState_ExpandTemplates(t *testing.T) {
+	workDir, err := os.Getwd()
+	if err != nil {
+		t.Error("failed to get a working directory with", err)
+		return
+	}
+
+	cmd, err := CommandState(&testParameter{
+		workDir:  "{{.Dir}}",
+		command:  "{{if .Instance}}false{{else}}true{{end}}",
+		args:     []string{"--run={{.Run}}", "--pid={{.PidFile}}"},
+		env:      []string{"TEST_INSTANCE={{.Instance}}"},
+		template: true,
+		pidDir:   "/run",
+	})
+	if err != nil {
+		t.Error("failed to create a command with", err)
+		return
+	}
+
+	if err := cmd.expandTemplates(); err == nil {
+		t.Error("failed to catch an error of an unknown field")
+	}
+
+	cmd.Init(&testParameter{
+		workDir:  workDir,
+		command:  "{{if .Instance}}false{{else}}true{{end}}",
+		args:     []string{"--run={{.Run}}", "--pid={{.PidFile}}"},
+		env:      []string{"TEST_INSTANCE={{.Instance}}"},
+		template: true,
+		pidDir:   "/run",
+	})
+
+	if err := cmd.expandTemplates(); err != nil {
+		t.Error("failed to expand templates with", err)
+		return
+	}
+
+	expectedArgs := []string{"true", "--run=1", "--pid=" + filepath.Join("/run", "true.0.pid")}
+	if !reflect.DeepEqual(cmd.cmd.Args, expectedArgs) {
+		t.Error("failed to expand arguments. Got", cmd.cmd.Args, ", but expected is", expectedArgs)
+	}
+
+	if filepath.Base(cmd.cmd.Path) != "true" || cmd.cmd.Err != nil {
+		t.Error("failed to look up an expanded command. Got", cmd.cmd.Path, cmd.cmd.Err)
+	}
+
+	if exist := cmd.cmd.Env[len(cmd.cmd.Env)-1]; exist != "TEST_INSTANCE=0" {
+		t.Error("failed to expand the environment. Got", exist, ", but expected is", "TEST_INSTANCE=0")
+	}
+}
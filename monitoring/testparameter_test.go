@@ -1,11 +1,36 @@
 package monitoring
 
+import (
+	"os"
+	"time"
+)
+
 type testParameter struct {
 	workDir       string
 	command       string
 	args          []string
 	runningMode   int32
 	parallelCount int32
+	restartPolicy RestartPolicy
+	stopSignal    os.Signal
+	stopTimeout   time.Duration
+	processGroup  bool
+	restartMode   RestartMode
+	successCodes  []int
+	stdErrIsOk    bool
+	stdoutSink    OutputSink
+	stderrSink    OutputSink
+	logFile       *LogFileConfig
+	healthCheck   *HealthConfig
+	readiness     *ReadinessConfig
+	env           []string
+	portRange     [2]int
+	template      bool
+	pidDir        string
+	resources     time.Duration
+	limits        *ResourceLimits
+	attrs         *ProcessAttrs
+	lifetime      *LifetimeConfig
 }
 
 func (o *testParameter) WorkDir() string {
@@ -29,9 +54,85 @@ func (o *testParameter) ParallelCount() int32 {
 }
 
 func (o *testParameter) StdErrIsOk() bool {
-	return false
+	return o.stdErrIsOk
 }
 
 func (o *testParameter) CheckStartLine() func(string) bool {
 	return nil
-}
\ No newline at end of file
+}
+
+func (o *testParameter) RestartPolicy() RestartPolicy {
+	return o.restartPolicy
+}
+
+func (o *testParameter) StopSignal() os.Signal {
+	return o.stopSignal
+}
+
+func (o *testParameter) StopTimeout() time.Duration {
+	return o.stopTimeout
+}
+
+func (o *testParameter) ProcessGroup() bool {
+	return o.processGroup
+}
+
+func (o *testParameter) RestartMode() RestartMode {
+	return o.restartMode
+}
+
+func (o *testParameter) SuccessExitCodes() []int {
+	return o.successCodes
+}
+
+func (o *testParameter) StdOutSink() OutputSink {
+	return o.stdoutSink
+}
+
+func (o *testParameter) StdErrSink() OutputSink {
+	return o.stderrSink
+}
+
+func (o *testParameter) LogFile() *LogFileConfig {
+	return o.logFile
+}
+
+func (o *testParameter) HealthCheck() *HealthConfig {
+	return o.healthCheck
+}
+
+func (o *testParameter) Readiness() *ReadinessConfig {
+	return o.readiness
+}
+
+func (o *testParameter) Env() []string {
+	return o.env
+}
+
+func (o *testParameter) PortRange() (int, int) {
+	return o.portRange[0], o.portRange[1]
+}
+
+func (o *testParameter) Template() bool {
+	return o.template
+}
+
+func (o *testParameter) PidDir() string {
+	return o.pidDir
+}
+
+func (o *testParameter) ResourceInterval() time.Duration {
+	return o.resources
+}
+
+func (o *testParameter) ResourceLimits() *ResourceLimits {
+	return o.limits
+}
+
+func (o *testParameter) ProcessAttrs() *ProcessAttrs {
+	return o.attrs
+}
+
+func (o *testParameter) Lifetime() *LifetimeConfig {
+	return o.lifetime
+}